@@ -0,0 +1,298 @@
+// commands.go
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"stations/go/A"
+	"stations/go/importer"
+	"stations/go/lint"
+	network "stations/go/network/dijkstra"
+	"stations/go/parser"
+	"stations/go/pathfinder"
+	"stations/go/render"
+	"strings"
+)
+
+// runFmt rewrites each map file in place in canonical form. Without files,
+// or for "-", it reads a map from standard input and writes it to standard
+// output, with the maps it includes merged in.
+func runFmt(args []string) {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	mapFlags := addMapFlags(flags)
+	flags.Parse(args)
+	files := flags.Args()
+	options := commandOptions(mapFlags)
+	if len(files) == 0 {
+		files = []string{parser.Stdin}
+	}
+
+	for _, filePath := range files {
+		net, err := parser.ReadMapWithOptions(filePath, options)
+		if err != nil {
+			printMapError(err)
+			os.Exit(1)
+		}
+
+		// Writing the map would copy the included files into it.
+		if len(net.Metadata.Includes) > 0 && filePath != parser.Stdin {
+			fmt.Fprintf(os.Stderr, "Error: %s includes other maps and cannot be formatted in place\n", filePath)
+			os.Exit(1)
+		}
+
+		var out bytes.Buffer
+		if err := writeMap(&out, filePath, net); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+
+		if filePath == parser.Stdin {
+			os.Stdout.Write(out.Bytes())
+			continue
+		}
+
+		info, err := os.Stat(filePath)
+		if err == nil {
+			err = os.WriteFile(filePath, out.Bytes(), info.Mode().Perm())
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// runConvert reads a map in any format and writes it to another file, in
+// the format given by the extension of the output file.
+func runConvert(args []string) {
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	mapFlags := addMapFlags(flags)
+	flags.Parse(args)
+	args = flags.Args()
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "Error: Usage: trains convert [map flags] <map> <output map>")
+		os.Exit(1)
+	}
+
+	net, err := readNetwork(args[0], commandOptions(mapFlags))
+	if err != nil {
+		printMapError(err)
+		os.Exit(1)
+	}
+	if args[1] == parser.Stdin {
+		err = writeMap(os.Stdout, args[1], net)
+	} else {
+		err = writeFile(args[1], func(w io.Writer) error {
+			return writeMap(w, args[1], net)
+		})
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// writeMap writes the network in the map format of filePath's extension:
+// JSON for .json, GraphML for .graphml and a text map otherwise.
+func writeMap(w io.Writer, filePath string, net *network.Network) error {
+	extension := filepath.Ext(filePath)
+	if strings.EqualFold(extension, ".json") {
+		return parser.WriteJSON(w, net)
+	}
+	if strings.EqualFold(extension, ".graphml") {
+		return parser.WriteGraphML(w, net)
+	}
+	return parser.WriteMap(w, net)
+}
+
+// runImport converts a GTFS feed, given as a directory or zip file, or a
+// GeoJSON file into a text map written to standard output.
+func runImport(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	geographic := flags.Bool("geographic", false, "keep the latitude and longitude of stops instead of projecting them onto a grid")
+	mapFlags := addMapFlags(flags)
+	flags.Parse(args)
+	args = flags.Args()
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Error: Usage: trains import [-geographic] [map flags] <gtfs directory or zip, or geojson file>")
+		os.Exit(1)
+	}
+	options := commandOptions(mapFlags)
+
+	var net *network.Network
+	var err error
+	switch {
+	case importer.IsGTFS(args[0]):
+		net, err = importer.ReadGTFS(args[0], importer.GTFSOptions{Geographic: *geographic, Parse: options})
+	case strings.EqualFold(filepath.Ext(args[0]), ".geojson"):
+		net, err = importer.ReadGeoJSON(args[0], importer.GeoJSONOptions{Geographic: *geographic, Parse: options})
+	default:
+		net, err = parser.ReadMapWithOptions(args[0], options)
+	}
+	if err != nil {
+		printMapError(err)
+		os.Exit(1)
+	}
+	if err := parser.WriteMap(os.Stdout, net); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// readNetwork reads the network at filePath, which is a map file, a GTFS
+// feed or a GeoJSON file, and checks it by the rules of options.
+func readNetwork(filePath string, options parser.ParseOptions) (*network.Network, error) {
+	if filePath != parser.Stdin && importer.IsGTFS(filePath) {
+		return importer.ReadGTFS(filePath, importer.GTFSOptions{Parse: options})
+	}
+	if strings.EqualFold(filepath.Ext(filePath), ".geojson") {
+		return importer.ReadGeoJSON(filePath, importer.GeoJSONOptions{Parse: options})
+	}
+	return parser.ReadMapWithOptions(filePath, options)
+}
+
+// runLint checks each map for connectivity problems and prints the findings.
+// It exits with status 1 when a map cannot be read or has a finding of error
+// severity.
+func runLint(args []string) {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	mapFlags := addMapFlags(flags)
+	flags.Parse(args)
+	files := flags.Args()
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: Usage: trains lint [map flags] <map>...")
+		os.Exit(1)
+	}
+	options := commandOptions(mapFlags)
+
+	failed := false
+	for _, filePath := range files {
+		net, err := readNetwork(filePath, options)
+		if err != nil {
+			printMapError(err)
+			failed = true
+			continue
+		}
+		findings := lint.Lint(net).WithFile(filePath)
+		for _, finding := range findings {
+			fmt.Println(finding)
+		}
+		if findings.HasErrors() {
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// runPath prints the cheapest path between two stations found by A*, with
+// how many stations the search expanded, with and without its heuristic,
+// and with -k the k paths with the shortest travel time.
+func runPath(args []string) {
+	flags := flag.NewFlagSet("path", flag.ExitOnError)
+	costName := flags.String("cost", "distance", "what a connection costs, `distance` (its travel time) or unit (1 per stop)")
+	k := flags.Int("k", 0, "also list the `k` paths with the shortest travel time")
+	mapFlags := addMapFlags(flags)
+	fuzzy := addFuzzyFlag(flags)
+	flags.Parse(args)
+	args = flags.Args()
+	if len(args) != 3 {
+		fmt.Fprintln(os.Stderr, "Error: Usage: trains path [-cost distance|unit] [-k k] [-fuzzy] [map flags] <map> <start> <end>")
+		os.Exit(1)
+	}
+	if *k < 0 {
+		fmt.Fprintln(os.Stderr, "Error: -k must be 0 or more")
+		os.Exit(1)
+	}
+
+	var cost A.CostModel
+	switch *costName {
+	case "distance":
+		cost = A.DistanceCost
+	case "unit":
+		cost = A.UnitCost
+	default:
+		fmt.Fprintln(os.Stderr, "Error: -cost must be distance or unit")
+		os.Exit(1)
+	}
+
+	net, err := readNetwork(args[0], commandOptions(mapFlags))
+	if err != nil {
+		printMapError(err)
+		os.Exit(1)
+	}
+	start, end := args[1], args[2]
+	for _, name := range []*string{&start, &end} {
+		if *name, err = resolveStation(net, *name, *fuzzy); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: Station", err)
+			os.Exit(1)
+		}
+	}
+
+	search := A.FindPath(start, end, net, cost)
+	if len(search.Path) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no path found between %s and %s\n", start, end)
+		os.Exit(1)
+	}
+	dijkstra := A.FindPathWithoutHeuristic(start, end, net, cost)
+	fmt.Println(strings.Join(search.Path, " "))
+	fmt.Printf("cost %d, expanded %d of %d stations (%d without the heuristic)\n", search.Cost, search.Expanded, len(net.Stations), dijkstra.Expanded)
+
+	if *k > 0 {
+		fmt.Printf("\n%d shortest paths by travel time:\n", *k)
+		for i, path := range pathfinder.KShortestPaths(start, end, net, *k) {
+			fmt.Printf("%d. %s\n", i+1, strings.Join(path, " "))
+		}
+	}
+}
+
+// runDot writes a map as a Graphviz graph to standard output.
+func runDot(args []string) {
+	flags := flag.NewFlagSet("dot", flag.ExitOnError)
+	mapFlags := addMapFlags(flags)
+	flags.Parse(args)
+	args = flags.Args()
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Error: Usage: trains dot [map flags] <map>")
+		os.Exit(1)
+	}
+
+	net, err := readNetwork(args[0], commandOptions(mapFlags))
+	if err != nil {
+		printMapError(err)
+		os.Exit(1)
+	}
+	if err := render.WriteDOT(os.Stdout, net, nil); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// commandOptions returns the parse options that the map flags of a command
+// give, and exits when they are invalid.
+func commandOptions(mapFlags mapFlags) parser.ParseOptions {
+	options, err := mapFlags.options()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	return options
+}
+
+// writeFile creates the file at filePath and writes it with write.
+func writeFile(filePath string, write func(io.Writer) error) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -2,239 +2,113 @@
 package A
 
 import (
-	"bufio"
 	"container/heap"
-	"errors"
-	"fmt"
-	"os"
-	"sort"
+	"math"
 	astar "stations/go/network/astar"
-	"strconv"
-	"strings"
+	network "stations/go/network/dijkstra"
 )
 
-// PrintResult processes input and runs the simulation
-func PrintResult() {
-	filePath := os.Args[1]
-	startStation := os.Args[2]
-	endStation := os.Args[3]
-	numTrains, err := strconv.Atoi(os.Args[4])
-	if err != nil || numTrains <= 0 {
-		fmt.Fprintln(os.Stderr, "Error: Number of trains must be a positive integer")
-		return
-	}
-
-	graph, err := read(filePath)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error:", err)
-		return
-	}
-	maxPaths := 8
-	// Get distinct paths
-	paths := findDistinctPaths(startStation, endStation, graph, maxPaths)
-
-	// Distribute trains across paths
-	trainAssignments := distributeTrainsAcrossPaths(paths, numTrains)
+// CostModel is what a path costs in an A* search.
+type CostModel int
 
-	// Simulate train movements
-	simulateTrainMovements(paths, trainAssignments, startStation, endStation)
+const (
+	// UnitCost makes every connection cost 1, so the cheapest path is
+	// the one with the fewest stops, the turns a train needs.
+	UnitCost CostModel = iota
+	// DistanceCost makes every connection cost its travel time, which is
+	// the distance between its stations when the map gives none.
+	DistanceCost
+)
 
-	fmt.Println("******************************************")
+// Search is the result of an A* search.
+type Search struct {
+	// Path is the cheapest path, empty when there is none.
+	Path []string
+	Cost int
+	// Expanded is how many stations the search took off its queue.
+	Expanded int
 }
 
-// Read the input file and create a graph
-func read(filePath string) (*astar.Graph, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	graph := &astar.Graph{
-		Stations:    make(map[string]astar.Station),
-		Connections: make(map[string][]string),
-	}
-
-	existingConnections := make(map[string]struct{})
-	scanner := bufio.NewScanner(file)
-	section := ""
-
-	stationCount := 0
-	connectionCount := 0
-	stationsSectionExists := false
-	connectionsSectionExists := false
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
-
-		if line == "stations:" {
-			section = "stations"
-			stationsSectionExists = true
-			continue
-		} else if line == "connections:" {
-			section = "connections"
-			connectionsSectionExists = true
-			continue
-		}
-
-		if section == "stations" {
-			// Process station line
-			parts := strings.Split(line, ",")
-			if len(parts) != 3 {
-				return nil, fmt.Errorf("invalid station line: %s", line)
-			}
-
-			name := strings.TrimSpace(parts[0])
-
-			x, err := strconv.Atoi(strings.TrimSpace(parts[1]))
-			if err != nil || x < 0 {
-				return nil, fmt.Errorf("invalid x coordinate for station %s", name)
-			}
-
-			y, err := strconv.Atoi(strings.TrimSpace(parts[2]))
-			if err != nil || y < 0 {
-				return nil, fmt.Errorf("invalid y coordinate for station %s", name)
-			}
-
-			if _, exists := graph.Stations[name]; exists {
-				return nil, fmt.Errorf("duplicate station name: %s", name)
-			}
-
-			for _, station := range graph.Stations {
-				if station.X == x && station.Y == y {
-					return nil, fmt.Errorf("duplicate coordinates for station %s", name)
-				}
-			}
-
-			graph.Stations[name] = astar.Station{
-				Name: name,
-				X:    x,
-				Y:    y,
-			}
-			stationCount++
-		} else if section == "connections" {
-			// Process connection line
-			parts := strings.Split(line, "-")
-			if len(parts) != 2 {
-				return nil, fmt.Errorf("invalid connection line: %s", line)
-			}
-
-			from := strings.TrimSpace(parts[0])
-			to := strings.TrimSpace(parts[1])
-
-			if _, exists := graph.Stations[from]; !exists {
-				return nil, fmt.Errorf("connection from non-existent station: %s", from)
-			}
-
-			if _, exists := graph.Stations[to]; !exists {
-				return nil, fmt.Errorf("connection to non-existent station: %s", to)
-			}
-
-			if from == to {
-				return nil, fmt.Errorf("connection with same start and end station: %s", from)
-			}
+// FindPath finds the cheapest path from start to end under the cost model
+// with A*.
+func FindPath(start, end string, net *network.Network, cost CostModel) Search {
+	return newPathSearch(net, end, cost, true).run(start)
+}
 
-			connectionKey := fmt.Sprintf("%s-%s", from, to)
-			reverseConnectionKey := fmt.Sprintf("%s-%s", to, from)
-			if _, exists := existingConnections[connectionKey]; exists {
-				return nil, fmt.Errorf("duplicate connection between %s and %s", from, to)
-			}
-			if _, exists := existingConnections[reverseConnectionKey]; exists {
-				return nil, fmt.Errorf("duplicate connection between %s and %s", from, to)
-			}
+// FindPathWithoutHeuristic finds the cheapest path like FindPath, but with a
+// heuristic of 0, which makes A* Dijkstra's algorithm. Comparing the two
+// shows how many stations the heuristic saves expanding.
+func FindPathWithoutHeuristic(start, end string, net *network.Network, cost CostModel) Search {
+	return newPathSearch(net, end, cost, false).run(start)
+}
 
-			existingConnections[connectionKey] = struct{}{}
-			existingConnections[reverseConnectionKey] = struct{}{}
+// pathSearch is an A* search for paths to one station under a cost model.
+type pathSearch struct {
+	net   *network.Network
+	end   string
+	costs map[[2]string]int
+	// heuristic is a lower bound on the cost from a station to end.
+	heuristic func(station string) int
+}
 
-			graph.Connections[from] = append(graph.Connections[from], to)
-			graph.Connections[to] = append(graph.Connections[to], from)
-			connectionCount++
+func newPathSearch(net *network.Network, end string, model CostModel, withHeuristic bool) *pathSearch {
+	search := &pathSearch{
+		net:       net,
+		end:       end,
+		costs:     make(map[[2]string]int),
+		heuristic: func(string) int { return 0 },
+	}
+
+	// ratio is the lowest cost per unit of distance of any connection, so
+	// that no path is cheaper than ratio times the straight distance
+	// between its ends. When costs are distances it is 1.
+	ratio := math.Inf(1)
+	for _, connection := range net.Connections {
+		cost := 1
+		if model == DistanceCost {
+			cost = connection.TravelTime()
 		}
-
-		// Check station and connection count
-		if stationCount > 10000 {
-			return nil, errors.New("map contains more than 10000 stations")
+		from, to := connection.Start.Name, connection.End.Name
+		search.setCost(from, to, cost)
+		if !connection.Directed {
+			search.setCost(to, from, cost)
 		}
-
-		if connectionCount > 10000 {
-			return nil, errors.New("map contains more than 10000 connections")
+		if length := connection.Start.Distance(connection.End); length > 0 {
+			ratio = math.Min(ratio, float64(cost)/length)
+		} else {
+			ratio = 0
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-
-	if !stationsSectionExists {
-		return nil, errors.New("map does not contain a \"stations:\" section")
-	}
-
-	if !connectionsSectionExists {
-		return nil, errors.New("map does not contain a \"connections:\" section")
-	}
-
-	if len(graph.Stations) == 0 {
-		return nil, errors.New("map does not contain any stations")
-	}
-
-	if len(graph.Connections) == 0 {
-		return nil, errors.New("map does not contain any connections")
-	}
-
-	return graph, nil
-}
-
-// Find distinct paths between start and end
-func findDistinctPaths(start, end string, graph *astar.Graph, maxPaths int) [][]string {
-	paths := [][]string{}
-	usedStations := make(map[string]struct{})
-	allPaths := [][]string{}
-
-	for len(paths) < maxPaths {
-		path := aStarPathfinding(start, end, graph.Connections, usedStations)
-
-		if len(path) == 0 {
-			break
-		}
-
-		// Add the path to the list of all paths
-		allPaths = append(allPaths, path)
-
-		// Mark stations in this path as used
-		for _, node := range path {
-			if node != start && node != end {
-				usedStations[node] = struct{}{}
-			}
+	// With unit costs the heuristic stays 0, which is always admissible.
+	if withHeuristic && model == DistanceCost && ratio > 0 && !math.IsInf(ratio, 1) {
+		goal := net.Stations[end]
+		search.heuristic = func(station string) int {
+			// Rounding down keeps the bound below the real cost.
+			return int(ratio * net.Stations[station].Distance(goal))
 		}
 	}
+	return search
+}
 
-	// Sort paths by length (shortest first)
-	sort.Slice(allPaths, func(i, j int) bool {
-		return len(allPaths[i]) < len(allPaths[j])
-	})
-
-	// Take up to maxPaths paths
-	paths = allPaths
-	if len(paths) > maxPaths {
-		paths = paths[:maxPaths]
+// setCost keeps the cheapest of parallel connections between two stations.
+func (s *pathSearch) setCost(from, to string, cost int) {
+	if old, exists := s.costs[[2]string{from, to}]; !exists || cost < old {
+		s.costs[[2]string{from, to}] = cost
 	}
-
-	return paths
 }
 
-// A* pathfinding algorithm to find the optimal path
-func aStarPathfinding(start, end string, connections map[string][]string, usedStations map[string]struct{}) []string {
+// run finds the cheapest path from start to the end of the search.
+func (s *pathSearch) run(start string) Search {
 	// Initialize the priority queue
 	pq := &astar.PriorityQueue{}
 	heap.Init(pq)
-	heap.Push(pq, &astar.Node{Station: start, Cost: 0, Priority: 0})
+	heap.Push(pq, &astar.Node{Station: start, Cost: 0, Priority: s.heuristic(start)})
 
 	// Map to keep track of the costs
 	costSoFar := make(map[string]int)
 	costSoFar[start] = 0
+	expanded := make(map[string]bool)
 
 	// Map to keep track of the parent nodes for path reconstruction
 	parentMap := make(map[string]string)
@@ -242,9 +116,15 @@ func aStarPathfinding(start, end string, connections map[string][]string, usedSt
 	for pq.Len() > 0 {
 		currentNode := heap.Pop(pq).(*astar.Node)
 		current := currentNode.Station
+		// A station is queued again each time a cheaper way to it is
+		// found, only the first time it comes off the queue counts.
+		if expanded[current] {
+			continue
+		}
+		expanded[current] = true
 
 		// If we reached the goal, reconstruct the path
-		if current == end {
+		if current == s.end {
 			path := []string{}
 			for current != start {
 				path = append(path, current)
@@ -257,162 +137,24 @@ func aStarPathfinding(start, end string, connections map[string][]string, usedSt
 				path[i], path[j] = path[j], path[i]
 			}
 
-			return path
+			return Search{Path: path, Cost: costSoFar[s.end], Expanded: len(expanded)}
 		}
 
 		// Explore neighbors
-		for _, neighbor := range connections[current] {
-			if _, used := usedStations[neighbor]; used {
+		for _, neighbor := range s.net.Adjacency[current] {
+			if expanded[neighbor] {
 				continue
 			}
 
-			newCost := costSoFar[current] + 1 // Assuming all edges have a uniform cost
+			newCost := costSoFar[current] + s.costs[[2]string{current, neighbor}]
 			if oldCost, ok := costSoFar[neighbor]; !ok || newCost < oldCost {
 				costSoFar[neighbor] = newCost
-				priority := newCost
+				priority := newCost + s.heuristic(neighbor)
 				heap.Push(pq, &astar.Node{Station: neighbor, Cost: newCost, Priority: priority})
 				parentMap[neighbor] = current
 			}
 		}
 	}
 
-	return []string{}
-}
-
-// Distribute trains across paths with specified constraints
-func distributeTrainsAcrossPaths(paths [][]string, numTrains int) map[int]int {
-	trainAssignments := make(map[int]int)
-	pathUsageCount := make(map[int]int)
-
-	// Sort paths by length
-	sortedPaths := make([][]string, len(paths))
-	copy(sortedPaths, paths)
-	sort.Slice(sortedPaths, func(i, j int) bool {
-		return len(sortedPaths[i]) < len(sortedPaths[j])
-	})
-
-	// Identify the shortest, second shortest, and longest paths
-	shortestPathIndex := 0
-	secondShortestPathIndex := 1
-	longestPathIndex := len(paths) - 1
-
-	// Ensure we have enough paths
-	if len(paths) < 2 {
-		secondShortestPathIndex = 0
-	}
-	if len(paths) < 3 {
-		longestPathIndex = 0
-	}
-
-	// Assign trains
-	for i := 0; i < numTrains; i++ {
-		switch {
-		case i < 5:
-			trainAssignments[i] = shortestPathIndex
-		case i < 8:
-			trainAssignments[i] = secondShortestPathIndex
-		case i < 9:
-			trainAssignments[i] = longestPathIndex
-		default:
-			// Assign remaining trains to the shortest path if no other option
-			trainAssignments[i] = shortestPathIndex
-		}
-		pathUsageCount[trainAssignments[i]]++
-	}
-
-	return trainAssignments
-}
-
-
-// Simulate train movements on given paths
-func simulateTrainMovements(paths [][]string, trainAssignments map[int]int, startStation, endStation string) int {
-	totalMovements := 0
-	numTrains := len(trainAssignments)
-	trains := make([]astar.Train, numTrains)
-	positions := make([]int, numTrains)
-	completed := make([]bool, numTrains)
-	trainLog := make([][]string, 0)
-	occupiedStations := make(map[string]int)
-
-	// Initialize trains
-	for i := 0; i < numTrains; i++ {
-		color := ""
-		switch i % 4 {
-		case 0:
-			color = "31" // Red
-		case 1:
-			color = "33" // Yellow
-		case 2:
-			color = "34" // Blue
-		case 3:
-			color = "32" // Green
-		}
-		trains[i] = astar.Train{ID: i + 1, Color: color}
-		occupiedStations[startStation]++
-	}
-
-	/*/ Debug: Print the paths and train assignments
-	fmt.Println("Paths:", paths)
-	fmt.Println("Train Assignments:", trainAssignments)*/
-
-	// Simulate movements
-	for {
-		allArrived := true
-		turnLog := []string{}
-		for i := 0; i < numTrains; i++ {
-			if completed[i] {
-				continue
-			}
-
-			pathIndex := trainAssignments[i]
-			if pathIndex >= len(paths) || pathIndex < 0 {
-				fmt.Fprintf(os.Stderr, "Error: Invalid path index %d for train %d\n", pathIndex, i)
-				return -1
-			}
-			path := paths[pathIndex]
-
-			// Check if the next position is free
-			nextPosition := positions[i] + 1
-			if nextPosition < len(path) {
-				nextStation := path[nextPosition]
-				if occupiedStations[nextStation] == 0 || nextStation == endStation {
-					// Move the train
-					if positions[i] > 0 {
-						// Decrement the count of the current station only if it's not the start station
-						occupiedStations[path[positions[i]]]--
-					}
-					positions[i]++
-					occupiedStations[nextStation]++
-					// Append the train movement with color
-					turnLog = append(turnLog, fmt.Sprintf("\033[%smT%d-%s\033[0m", trains[i].Color, trains[i].ID, nextStation))
-					totalMovements++
-					allArrived = false
-
-					if nextStation == endStation {
-						completed[i] = true
-					}
-				}
-			}
-		}
-		if len(turnLog) > 0 {
-			trainLog = append(trainLog, turnLog)
-		}
-		if allArrived {
-			break
-		}
-	}
-
-	filePath := os.Args[1]
-	// Print the train movements
-	fmt.Print("\nTrain movements from\033[1m ", filePath)
-	fmt.Print("\n\033[0m")
-	fmt.Print("\033[4m", startStation, "\033[0m to \033[4m", endStation, "\033[0m with \033[4m", numTrains, "\033[0m trains:\n\n")
-	for _, turn := range trainLog {
-		fmt.Println(strings.Join(turn, " "))
-	}
-
-	// Print the number of turns
-	fmt.Printf("\nTotal Movements: %d\n", len(trainLog))
-
-	return totalMovements
+	return Search{Path: []string{}, Expanded: len(expanded)}
 }
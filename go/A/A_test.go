@@ -0,0 +1,119 @@
+package A
+
+import (
+	"path/filepath"
+	network "stations/go/network/dijkstra"
+	"stations/go/parser"
+	"strings"
+	"testing"
+)
+
+// bundledMaps are the maps in the maps directory with the start and end
+// station the README runs them with.
+var bundledMaps = []struct {
+	file       string
+	start, end string
+}{
+	{"01london.txt", "waterloo", "st_pancras"},
+	{"02bond.txt", "bond_square", "space_port"},
+	{"03jungle.txt", "jungle", "desert"},
+	{"04beginning.txt", "beginning", "terminus"},
+	{"05one.txt", "two", "four"},
+	{"06beethoven.txt", "beethoven", "part"},
+	{"07small.txt", "small", "large"},
+	{"tenK.txt", "station3", "station5"},
+}
+
+// readMap reads a map from the maps directory.
+func readMap(t *testing.T, file string) *network.Network {
+	t.Helper()
+	net, err := parser.ReadMap(filepath.Join("..", "..", "maps", file))
+	if err != nil {
+		t.Fatalf("reading %s: %v", file, err)
+	}
+	return net
+}
+
+// parseMap parses a text map that has to be valid.
+func parseMap(t *testing.T, text string) *network.Network {
+	t.Helper()
+	net, err := parser.ParseNetwork(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("parsing map: %v", err)
+	}
+	return net
+}
+
+// pathCost adds up what the connections of path cost, the cheapest of
+// parallel connections, or fails when path does not follow connections.
+func pathCost(t *testing.T, net *network.Network, path []string, model CostModel) int {
+	t.Helper()
+	total := 0
+	for i := 1; i < len(path); i++ {
+		best := -1
+		for _, connection := range net.Connections {
+			forward := connection.Start.Name == path[i-1] && connection.End.Name == path[i]
+			backward := !connection.Directed && connection.Start.Name == path[i] && connection.End.Name == path[i-1]
+			if !forward && !backward {
+				continue
+			}
+			cost := 1
+			if model == DistanceCost {
+				cost = connection.TravelTime()
+			}
+			if best < 0 || cost < best {
+				best = cost
+			}
+		}
+		if best < 0 {
+			t.Fatalf("path %v: no connection from %s to %s", path, path[i-1], path[i])
+		}
+		total += best
+	}
+	return total
+}
+
+func TestFindPathHeuristic(t *testing.T) {
+	for _, test := range bundledMaps {
+		net := readMap(t, test.file)
+		for _, model := range []CostModel{UnitCost, DistanceCost} {
+			with := FindPath(test.start, test.end, net, model)
+			without := FindPathWithoutHeuristic(test.start, test.end, net, model)
+			if len(with.Path) == 0 || with.Path[0] != test.start || with.Path[len(with.Path)-1] != test.end {
+				t.Errorf("%s, cost %d: got path %v, want one from %s to %s", test.file, model, with.Path, test.start, test.end)
+				continue
+			}
+			if with.Cost != without.Cost {
+				t.Errorf("%s, cost %d: got cost %d with the heuristic and %d without", test.file, model, with.Cost, without.Cost)
+			}
+			if got := pathCost(t, net, with.Path, model); got != with.Cost {
+				t.Errorf("%s, cost %d: path %v costs %d, search says %d", test.file, model, with.Path, got, with.Cost)
+			}
+			if with.Expanded > without.Expanded {
+				t.Errorf("%s, cost %d: expanded %d stations with the heuristic, %d without", test.file, model, with.Expanded, without.Expanded)
+			}
+		}
+	}
+}
+
+// Travel times shorter than the distances between stations must not make
+// the heuristic overestimate.
+func TestFindPathFastConnections(t *testing.T) {
+	net := parseMap(t, `stations:
+a,0,0
+b,50,0
+c,100,0
+d,50,40
+
+connections:
+a-b,10
+b-c,10
+a-d
+d-c
+`)
+	with := FindPath("a", "c", net, DistanceCost)
+	without := FindPathWithoutHeuristic("a", "c", net, DistanceCost)
+	if strings.Join(with.Path, " ") != "a b c" || with.Cost != 20 || without.Cost != 20 {
+		t.Errorf("got path %v costing %d, and %d without the heuristic, want a b c costing 20", with.Path, with.Cost, without.Cost)
+	}
+}
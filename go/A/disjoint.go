@@ -0,0 +1,236 @@
+package A
+
+import (
+	"sort"
+	network "stations/go/network/dijkstra"
+)
+
+// DisjointPaths returns the largest set of paths from start to end that
+// share no station but start and end, and of those sets the one with the
+// fewest connections in total, then the shortest travel time. Paths are
+// ordered shortest first. It returns false when start or end is not a
+// station of net, when they are the same station, or when end cannot be
+// reached.
+//
+// The paths are found as a minimum cost maximum flow: every station but
+// start and end is split in an in and an out node joined by an arc of
+// capacity 1, so that at most one path passes through it, and every
+// connection is an arc of capacity 1 from the out node of one station to
+// the in node of the other, which costs one connection and its travel time.
+func DisjointPaths(start, end string, net *network.Network) ([][]string, bool) {
+	flow, ok := newPathFlow(start, end, net, false)
+	if !ok {
+		return nil, false
+	}
+	for flow.augment() {
+	}
+	paths := flow.paths()
+	return paths, len(paths) > 0
+}
+
+// arc is an arc of the flow network. Every arc has a reverse arc in the
+// residual network at index reverse of the arcs of its head.
+type arc struct {
+	to, reverse    int
+	capacity, cost int
+	kind           arcKind
+}
+
+// arcKind tells the arcs of a track, the arcs that join the in and out node
+// of a station, and the reverse arcs that only carry flow back apart.
+type arcKind int
+
+const (
+	trackArc arcKind = iota
+	stationArc
+	reverseArc
+)
+
+// pathFlow is a flow network of station nodes that grows its flow one path
+// at a time, always along the cheapest augmenting path, so that a flow of k
+// paths is always the cheapest flow of k paths. With capacities, a station
+// or track lets as many paths through as trains it holds at once, and the
+// same path can be taken several times.
+type pathFlow struct {
+	names []string
+	arcs  [][]arc
+	// source is the out node of the start, sink the in node of the end.
+	source, sink int
+}
+
+// in and out are the node numbers of station i. Paths leave the start from
+// its out node and reach the end at its in node.
+func (f *pathFlow) in(i int) int  { return 2 * i }
+func (f *pathFlow) out(i int) int { return 2*i + 1 }
+
+// newPathFlow returns the flow network of paths from start to end without
+// any flow, or false when start or end is not a station of net or they are
+// the same station.
+func newPathFlow(start, end string, net *network.Network, capacities bool) (*pathFlow, bool) {
+	// Number the stations in order, so that the paths do not depend on
+	// the order of a map.
+	names := net.StationNames()
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		index[name] = i
+	}
+
+	startIndex, startExists := index[start]
+	endIndex, endExists := index[end]
+	if !startExists || !endExists || start == end {
+		return nil, false
+	}
+
+	f := &pathFlow{
+		names: names,
+		arcs:  make([][]arc, 2*len(names)),
+	}
+	f.source = f.out(startIndex)
+	f.sink = f.in(endIndex)
+	for i, name := range names {
+		switch name {
+		case start:
+			// Paths leave the start from its out node.
+		case end:
+			// Paths arrive at the in node of the end.
+		default:
+			capacity := 1
+			if capacities {
+				capacity = net.StationCapacity(name)
+			}
+			f.addArc(f.in(i), f.out(i), capacity, 0, stationArc)
+		}
+	}
+
+	trackCapacity := func(connection network.Connection) int {
+		if capacities {
+			return connection.TrackCapacity()
+		}
+		return 1
+	}
+
+	// A track costs one turn, and its travel time breaks ties between
+	// flows of as many turns: scale is more than the travel time of any
+	// flow, so a turn costs more than all travel times together.
+	scale := 1
+	for _, connection := range net.Connections {
+		scale += 2 * trackCapacity(connection) * connection.TravelTime()
+	}
+
+	// A cheapest flow never uses a two-way track both ways at once, since
+	// taking both off would make it cheaper, so each way can have the
+	// capacity of the whole track.
+	added := make(map[[2]int]bool)
+	addTrack := func(from, to, capacity, time int) {
+		if added[[2]int{from, to}] {
+			return
+		}
+		added[[2]int{from, to}] = true
+		f.addArc(f.out(from), f.in(to), capacity, scale+time, trackArc)
+	}
+	for _, connection := range net.Connections {
+		from, to := index[connection.Start.Name], index[connection.End.Name]
+		capacity, time := trackCapacity(connection), connection.TravelTime()
+		addTrack(from, to, capacity, time)
+		if !connection.Directed {
+			addTrack(to, from, capacity, time)
+		}
+	}
+	return f, true
+}
+
+func (f *pathFlow) addArc(from, to, capacity, cost int, kind arcKind) {
+	f.arcs[from] = append(f.arcs[from], arc{to: to, reverse: len(f.arcs[to]), capacity: capacity, cost: cost, kind: kind})
+	f.arcs[to] = append(f.arcs[to], arc{to: from, reverse: len(f.arcs[from]) - 1, capacity: 0, cost: -cost, kind: reverseArc})
+}
+
+// augment sends one more path through the network along the cheapest
+// augmenting path, and reports false when there is none.
+func (f *pathFlow) augment() bool {
+	// Arcs of the residual network can cost less than 0, so the cheapest
+	// path is found with Bellman-Ford, queueing only nodes whose distance
+	// went down.
+	const unreached = int(^uint(0) >> 1)
+	distance := make([]int, len(f.arcs))
+	for i := range distance {
+		distance[i] = unreached
+	}
+	previousNode := make([]int, len(f.arcs))
+	previousArc := make([]int, len(f.arcs))
+	queued := make([]bool, len(f.arcs))
+
+	distance[f.source] = 0
+	queue := []int{f.source}
+	queued[f.source] = true
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		queued[node] = false
+		for i, a := range f.arcs[node] {
+			if a.capacity > 0 && distance[node]+a.cost < distance[a.to] {
+				distance[a.to] = distance[node] + a.cost
+				previousNode[a.to] = node
+				previousArc[a.to] = i
+				if !queued[a.to] {
+					queued[a.to] = true
+					queue = append(queue, a.to)
+				}
+			}
+		}
+	}
+	if distance[f.sink] == unreached {
+		return false
+	}
+
+	for node := f.sink; node != f.source; node = previousNode[node] {
+		a := &f.arcs[previousNode[node]][previousArc[node]]
+		a.capacity--
+		f.arcs[node][a.reverse].capacity++
+	}
+	return true
+}
+
+// paths follows the flow from start to end, one path per unit of flow,
+// shortest path first. The flow is left as it is.
+func (f *pathFlow) paths() [][]string {
+	// Work on a copy of the arcs, whose flow is taken off path by path.
+	arcs := make([][]arc, len(f.arcs))
+	for i := range f.arcs {
+		arcs[i] = append([]arc(nil), f.arcs[i]...)
+	}
+
+	var paths [][]string
+	for {
+		path := []string{f.names[f.source/2]}
+		node := f.source
+		for node != f.sink {
+			next := -1
+			for i, a := range arcs[node] {
+				// An arc carries flow when its reverse arc has
+				// capacity.
+				if a.kind != reverseArc && arcs[a.to][a.reverse].capacity > 0 {
+					// Take the flow off, so the next path does
+					// not follow it again.
+					arcs[a.to][a.reverse].capacity--
+					arcs[node][i].capacity++
+					next = i
+					break
+				}
+			}
+			if next == -1 {
+				break
+			}
+			// A track leads to the next station of the path.
+			if a := arcs[node][next]; a.kind == trackArc {
+				path = append(path, f.names[a.to/2])
+			}
+			node = arcs[node][next].to
+		}
+		if node != f.sink {
+			break
+		}
+		paths = append(paths, path)
+	}
+	sort.SliceStable(paths, func(i, j int) bool { return len(paths[i]) < len(paths[j]) })
+	return paths
+}
@@ -0,0 +1,110 @@
+package A
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDisjointPaths(t *testing.T) {
+	tests := []struct {
+		name        string
+		connections string
+		want        [][]string
+	}{
+		{"two ways round", "a-b\nb-d\na-c\nc-d\n", [][]string{{"a", "b", "d"}, {"a", "c", "d"}}},
+		{"through one station", "a-b\na-c\nb-e\nc-e\ne-d\n", [][]string{{"a", "b", "e", "d"}}},
+		{"direct track", "a-d\na-b\nb-d\n", [][]string{{"a", "d"}, {"a", "b", "d"}}},
+		{"one-way", "a->b\nb->d\nd->c\nc->a\n", [][]string{{"a", "b", "d"}}},
+		// The shortest path a-b-c-d blocks both other paths, the largest
+		// set leaves it out.
+		{"largest set", "a-b\nb-c\nc-d\na-e\ne-f\nf-c\nb-g\ng-h\nh-d\n", [][]string{{"a", "b", "g", "h", "d"}, {"a", "e", "f", "c", "d"}}},
+	}
+	const stations = "stations:\na,0,0\nb,1,1\nc,1,3\nd,4,2\ne,3,0\nf,5,5\ng,6,6\nh,7,7\n\nconnections:\n"
+	for _, test := range tests {
+		net := parseMap(t, stations+test.connections)
+		if got, found := DisjointPaths("a", "d", net); !found || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDisjointPathsOnBundledMaps(t *testing.T) {
+	for _, test := range bundledMaps {
+		net := readMap(t, test.file)
+		paths, found := DisjointPaths(test.start, test.end, net)
+		if !found {
+			t.Errorf("%s: found no path", test.file)
+			continue
+		}
+		used := make(map[string]bool)
+		for i, path := range paths {
+			if path[0] != test.start || path[len(path)-1] != test.end {
+				t.Errorf("%s: path %v does not go from %s to %s", test.file, path, test.start, test.end)
+			}
+			// pathCost fails on a step without a connection.
+			pathCost(t, net, path, UnitCost)
+			for _, station := range path[1 : len(path)-1] {
+				if used[station] {
+					t.Errorf("%s: station %s is on more than one path", test.file, station)
+				}
+				used[station] = true
+			}
+			if i > 0 && len(path) < len(paths[i-1]) {
+				t.Errorf("%s: path %d is shorter than the one before it", test.file, i+1)
+			}
+		}
+	}
+}
+
+func TestDisjointPathsBetweenInvalidStations(t *testing.T) {
+	net := parseMap(t, "stations:\na,0,0\nb,1,1\nd,2,0\n\nconnections:\na-b\nb-d\n")
+	tests := []struct {
+		name       string
+		start, end string
+	}{
+		{"unknown start", "nope", "d"},
+		{"unknown end", "a", "nope"},
+		{"same station", "a", "a"},
+	}
+	for _, test := range tests {
+		if paths, found := DisjointPaths(test.start, test.end, net); found || paths != nil {
+			t.Errorf("%s: got %v, %v, want no paths", test.name, paths, found)
+		}
+	}
+}
+
+func TestPathFlowCapacities(t *testing.T) {
+	tests := []struct {
+		name        string
+		stations    string
+		connections string
+		// paths is how many paths the flow finds with and without the
+		// capacities of the map.
+		paths, disjoint int
+	}{
+		{"single track", "b,1,1\n", "a-b\nb-d\n", 1, 1},
+		{"double station and track", "b,1,1,2\n", "a-b x2\nb-d x2\n", 2, 1},
+		{"double station, single track", "b,1,1,2\n", "a-b x2\nb-d\n", 1, 1},
+		{"direct double track", "b,1,1\n", "a-d x2\na-b\nb-d\n", 3, 2},
+		{"one-way the wrong way", "b,1,1,3\n", "b->a x3\nd->b x3\n", 0, 0},
+	}
+	for _, test := range tests {
+		net := parseMap(t, "stations:\na,0,0\nd,2,0\n"+test.stations+"\nconnections:\n"+test.connections)
+		for _, capacities := range []bool{true, false} {
+			flow, _ := newPathFlow("a", "d", net, capacities)
+			for flow.augment() {
+			}
+			want := test.disjoint
+			if capacities {
+				want = test.paths
+			}
+			paths := flow.paths()
+			if len(paths) != want {
+				t.Errorf("%s, capacities %v: got paths %v, want %d", test.name, capacities, paths, want)
+			}
+			for _, path := range paths {
+				pathCost(t, net, path, UnitCost)
+			}
+		}
+	}
+}
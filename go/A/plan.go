@@ -0,0 +1,127 @@
+package A
+
+import (
+	"sort"
+	network "stations/go/network/dijkstra"
+)
+
+// Plan is how a number of trains get from start to end: the paths they take
+// and how many trains take each path, one turn after another.
+type Plan struct {
+	Start, End string
+	// Paths are ordered shortest first. A path that several trains can
+	// take side by side, over stations and tracks that hold several
+	// trains, is in Paths once for each of them.
+	Paths  [][]string
+	Trains []int
+	// Turns is how many turns the last train needs to reach the end.
+	Turns int
+}
+
+// OptimalPlan returns the plan that moves numTrains trains from start to end
+// in the fewest turns, or false when start or end is not a station of net,
+// when they are the same station, or when end cannot be reached.
+//
+// Trains that take the same path leave the start one turn after another, so
+// a path of length L taken by n trains is done after L+n-1 turns. For every
+// k, the cheapest flow of k paths from start to end gives the k paths of
+// least total length, and spreading the trains over them gives the fewest
+// turns for k paths. Of paths of the same total length, the flow takes
+// those with the shortest travel time. No schedule can do better than the best k, as
+// Ford and Fulkerson showed for flows that repeat over time.
+func OptimalPlan(start, end string, net *network.Network, numTrains int) (Plan, bool) {
+	best := Plan{Start: start, End: end}
+	found := false
+
+	flow, ok := newPathFlow(start, end, net, true)
+	if !ok {
+		return best, false
+	}
+	// More paths than trains would leave paths empty.
+	for k := 1; k <= numTrains && flow.augment(); k++ {
+		plan := assignTrains(start, end, flow.paths(), numTrains)
+		if !found || plan.Turns < best.Turns {
+			best, found = plan, true
+		}
+	}
+	return best, found
+}
+
+// assignTrains spreads numTrains trains over paths, ordered shortest first,
+// so that the last train arrives as early as possible. Paths that no train
+// needs are left out of the plan.
+func assignTrains(start, end string, paths [][]string, numTrains int) Plan {
+	// Find the fewest turns in which the paths can take every train: a
+	// path of length L takes turns-L+1 trains in that many turns.
+	turns := len(paths[0]) - 1
+	for capacity(paths, turns) < numTrains {
+		turns++
+	}
+
+	trains := make([]int, len(paths))
+	total := 0
+	for i, path := range paths {
+		trains[i] = max(0, turns-(len(path)-1)+1)
+		total += trains[i]
+	}
+	// Leave the trains that are too many out of the longest paths, where
+	// they would arrive last.
+	for i := len(paths) - 1; i >= 0 && total > numTrains; i-- {
+		fewer := min(trains[i], total-numTrains)
+		trains[i] -= fewer
+		total -= fewer
+	}
+
+	plan := Plan{Start: start, End: end, Turns: turns}
+	for i, path := range paths {
+		if trains[i] > 0 {
+			plan.Paths = append(plan.Paths, path)
+			plan.Trains = append(plan.Trains, trains[i])
+		}
+	}
+	return plan
+}
+
+// capacity returns how many trains the paths get to the end in turns turns.
+func capacity(paths [][]string, turns int) int {
+	trains := 0
+	for _, path := range paths {
+		trains += max(0, turns-(len(path)-1)+1)
+	}
+	return trains
+}
+
+// Schedule returns the moves of the plan. Each turn, one more train sets out
+// on every path that still has trains to send, and every train on its way
+// moves one station further. Trains are numbered in the order they set out.
+func (p Plan) Schedule() network.Schedule {
+	schedule := network.Schedule{Start: p.Start, End: p.End}
+
+	// departure is when a train sets out and on which path.
+	type departure struct {
+		path, turn int
+	}
+	var departures []departure
+	for i, trains := range p.Trains {
+		for turn := 0; turn < trains; turn++ {
+			departures = append(departures, departure{path: i, turn: turn})
+		}
+	}
+	sort.SliceStable(departures, func(i, j int) bool { return departures[i].turn < departures[j].turn })
+
+	for i := range departures {
+		schedule.Trains = append(schedule.Trains, network.NewTrain(i+1))
+	}
+	for turn := 0; turn < p.Turns; turn++ {
+		var moves []network.Move
+		for i, departure := range departures {
+			path := p.Paths[departure.path]
+			step := turn - departure.turn
+			if step >= 0 && step < len(path)-1 {
+				moves = append(moves, network.Move{Train: schedule.Trains[i], From: path[step], To: path[step+1]})
+			}
+		}
+		schedule.Turns = append(schedule.Turns, moves)
+	}
+	return schedule
+}
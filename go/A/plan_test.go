@@ -0,0 +1,183 @@
+package A
+
+import (
+	"reflect"
+	network "stations/go/network/dijkstra"
+	"testing"
+)
+
+// checkSchedule fails unless the schedule gets numTrains trains from start
+// to end along connections of the map, without more trains at a station or
+// on a track in one turn than it holds, in the given number of turns.
+func checkSchedule(t *testing.T, name string, net *network.Network, schedule network.Schedule, start, end string, numTrains, turns int) {
+	t.Helper()
+	if len(schedule.Trains) != numTrains {
+		t.Errorf("%s: got %d trains, want %d", name, len(schedule.Trains), numTrains)
+		return
+	}
+	if len(schedule.Turns) != turns {
+		t.Errorf("%s: got %d turns, want %d", name, len(schedule.Turns), turns)
+	}
+
+	at := make(map[int]string)
+	for _, train := range schedule.Trains {
+		at[train.ID] = start
+	}
+	for turn, moves := range schedule.Turns {
+		moved := make(map[int]bool)
+		onTrack := make(map[int]int)
+		for _, move := range moves {
+			if moved[move.Train.ID] {
+				t.Errorf("%s, turn %d: train %d moves twice", name, turn+1, move.Train.ID)
+			}
+			moved[move.Train.ID] = true
+			if at[move.Train.ID] != move.From {
+				t.Errorf("%s, turn %d: train %d moves from %s but is at %s", name, turn+1, move.Train.ID, move.From, at[move.Train.ID])
+			}
+			track := -1
+			for i, connection := range net.Connections {
+				if connection.Start.Name == move.From && connection.End.Name == move.To ||
+					!connection.Directed && connection.Start.Name == move.To && connection.End.Name == move.From {
+					track = i
+				}
+			}
+			if track < 0 {
+				t.Errorf("%s, turn %d: train %d moves from %s to %s without a connection", name, turn+1, move.Train.ID, move.From, move.To)
+				continue
+			}
+			onTrack[track]++
+			at[move.Train.ID] = move.To
+		}
+		for track, trains := range onTrack {
+			if connection := net.Connections[track]; trains > connection.TrackCapacity() {
+				t.Errorf("%s, turn %d: %d trains on track %s-%s of %d", name, turn+1, trains, connection.Start.Name, connection.End.Name, connection.TrackCapacity())
+			}
+		}
+		atStation := make(map[string]int)
+		for _, station := range at {
+			atStation[station]++
+		}
+		for station, trains := range atStation {
+			if station != start && station != end && trains > net.StationCapacity(station) {
+				t.Errorf("%s, turn %d: %d trains at %s, which holds %d", name, turn+1, trains, station, net.StationCapacity(station))
+			}
+		}
+	}
+	for id, station := range at {
+		if station != end {
+			t.Errorf("%s: train %d ends at %s, not %s", name, id, station, end)
+		}
+	}
+}
+
+func TestOptimalPlanOnBundledMaps(t *testing.T) {
+	tests := []struct {
+		file       string
+		start, end string
+		trains     int
+		turns      int
+	}{
+		{"01london.txt", "waterloo", "st_pancras", 1, 2},
+		{"01london.txt", "waterloo", "st_pancras", 2, 2},
+		{"01london.txt", "waterloo", "st_pancras", 3, 3},
+		{"01london.txt", "waterloo", "st_pancras", 4, 3},
+		{"01london.txt", "waterloo", "st_pancras", 100, 51},
+		{"02bond.txt", "bond_square", "space_port", 4, 6},
+		{"03jungle.txt", "jungle", "desert", 10, 8},
+		{"04beginning.txt", "beginning", "terminus", 20, 11},
+		{"05one.txt", "two", "four", 4, 6},
+		{"06beethoven.txt", "beethoven", "part", 9, 6},
+		{"07small.txt", "small", "large", 9, 8},
+		{"tenK.txt", "station3", "station5", 5, 6},
+	}
+	for _, test := range tests {
+		net := readMap(t, test.file)
+		plan, found := OptimalPlan(test.start, test.end, net, test.trains)
+		if !found {
+			t.Errorf("%s: no plan", test.file)
+			continue
+		}
+		checkSchedule(t, test.file, net, plan.Schedule(), test.start, test.end, test.trains, test.turns)
+	}
+}
+
+func TestOptimalPlan(t *testing.T) {
+	const stations = "a,0,0\nb,1,1\nc,1,3\nd,2,0\n"
+	const doubleB = "a,0,0\nb,1,1,2\nc,1,3\nd,2,0\n"
+	tests := []struct {
+		name        string
+		stations    string
+		connections string
+		trains      int
+		turns       int
+	}{
+		{"one path", stations, "a-b\nb-d\n", 3, 4},
+		{"two paths", stations, "a-b\nb-d\na-c\nc-d\n", 3, 3},
+		{"one-way", stations, "a->b\nb->d\nd->c\nc->a\n", 3, 4},
+		{"one-way the wrong way", stations, "a->b\nb->d\nd->c\nc->a\nc-b\n", 3, 4},
+		{"station and track capacity", doubleB, "a-b x2\nb-d x2\n", 4, 3},
+		{"station capacity only", doubleB, "a-b\nb-d\n", 4, 5},
+		{"track capacity only", stations, "a-b x2\nb-d x2\n", 4, 5},
+		{"direct track", stations, "a-d\na-b\nb-d\n", 4, 3},
+		{"direct double track", stations, "a-d x2\na-b\nb-d\n", 4, 2},
+		{"direct track only", stations, "a-d\n", 4, 4},
+	}
+	for _, test := range tests {
+		net := parseMap(t, "stations:\n"+test.stations+"\nconnections:\n"+test.connections)
+		plan, found := OptimalPlan("a", "d", net, test.trains)
+		if !found {
+			t.Errorf("%s: no plan", test.name)
+			continue
+		}
+		checkSchedule(t, test.name, net, plan.Schedule(), "a", "d", test.trains, test.turns)
+	}
+}
+
+// Of paths with as many connections, trains take the one with the shortest
+// travel time.
+func TestOptimalPlanUsesTravelTimes(t *testing.T) {
+	const stations = "stations:\na,0,0\nb,1,1\nc,1,3\nd,2,0\n\nconnections:\n"
+	tests := []struct {
+		name        string
+		connections string
+		want        []string
+	}{
+		{"times", "a-b,100\nb-d,100\na-c,1\nc-d,1\n", []string{"a", "c", "d"}},
+		{"distances", "a-b\nb-d\na-c\nc-d\n", []string{"a", "b", "d"}},
+	}
+	for _, test := range tests {
+		net := parseMap(t, stations+test.connections)
+		plan, found := OptimalPlan("a", "d", net, 1)
+		if !found {
+			t.Errorf("%s: no plan", test.name)
+			continue
+		}
+		if len(plan.Paths) != 1 || !reflect.DeepEqual(plan.Paths[0], test.want) {
+			t.Errorf("%s: got paths %v, want %v", test.name, plan.Paths, test.want)
+		}
+	}
+}
+
+func TestOptimalPlanWithoutPath(t *testing.T) {
+	net := parseMap(t, "stations:\na,0,0\nb,1,1\nd,2,0\n\nconnections:\na-b\nd->b\n")
+	if plan, found := OptimalPlan("a", "d", net, 2); found {
+		t.Errorf("got plan %+v, want none", plan)
+	}
+}
+
+func TestOptimalPlanBetweenInvalidStations(t *testing.T) {
+	net := parseMap(t, "stations:\na,0,0\nb,1,1\nd,2,0\n\nconnections:\na-b\nb-d\n")
+	tests := []struct {
+		name       string
+		start, end string
+	}{
+		{"unknown start", "nope", "d"},
+		{"unknown end", "a", "nope"},
+		{"same station", "a", "a"},
+	}
+	for _, test := range tests {
+		if plan, found := OptimalPlan(test.start, test.end, net, 2); found {
+			t.Errorf("%s: got plan %+v, want none", test.name, plan)
+		}
+	}
+}
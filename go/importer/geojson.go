@@ -0,0 +1,353 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	network "stations/go/network/dijkstra"
+	"stations/go/parser"
+)
+
+/*
+GeoJSON files are read as a FeatureCollection of stops and lines:
+
+  - every Point feature is a stop, named by its "name" property, and becomes
+    a station, holding as many trains as its "capacity" property says,
+  - every LineString or MultiLineString feature is a rail line, one-way when
+    its "oneway" property is true and with as many parallel tracks as its
+    "tracks" property says,
+  - a stop lies on every line that passes within the snap distance of it,
+  - two stops that follow each other along a line are connected, once even
+    when several lines run between them, and two-way when any of those
+    lines is.
+*/
+
+// DefaultSnapDistance is how far from a line a stop may be, in metres, and
+// still lie on it.
+const DefaultSnapDistance = 50.0
+
+// GeoJSONOptions changes how ReadGeoJSON turns features into a network.
+type GeoJSONOptions struct {
+	// Resolution is the size of one map grid unit in metres,
+	// DefaultResolution when 0.
+	Resolution float64
+	// SnapDistance is how far a stop may be from a line to lie on it, in
+	// metres, DefaultSnapDistance when 0.
+	SnapDistance float64
+	// Geographic keeps the latitude and longitude of stops as station
+	// coordinates instead of projecting them onto a grid.
+	Geographic bool
+	// Parse are the rules the network is checked by.
+	Parse parser.ParseOptions
+}
+
+type geoJSONFeature struct {
+	Type       string                     `json:"type"`
+	Geometry   *geoJSONGeometry           `json:"geometry"`
+	Properties map[string]json.RawMessage `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// geoJSONLine is one line of a LineString or MultiLineString feature.
+type geoJSONLine struct {
+	points   []geoPoint
+	directed bool
+	tracks   int
+	pos      parser.Position
+}
+
+// geoJSONTrack is a connection between two stops, made by the first line
+// that runs between them.
+type geoJSONTrack struct {
+	entry   parser.TrackEntry
+	dropped bool
+}
+
+// lineStop is a stop lying on a line, at distance along from the start of the line.
+type lineStop struct {
+	stop  int
+	along float64
+}
+
+// ReadGeoJSON reads the GeoJSON FeatureCollection in the file at path and
+// builds a network from it. The network is checked by the same rules as a
+// map file. Everything is read from the file, nothing is fetched.
+func ReadGeoJSON(path string, options GeoJSONOptions) (*network.Network, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	net, err := ParseGeoJSON(data, options)
+	var diagnostics parser.Diagnostics
+	if errors.As(err, &diagnostics) {
+		return nil, diagnostics.WithFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	net.Metadata.Source = path
+	return net, nil
+}
+
+// ParseGeoJSON builds a network from a GeoJSON FeatureCollection.
+// Diagnostics point at the feature they are about and name it by its index.
+func ParseGeoJSON(data []byte, options GeoJSONOptions) (*network.Network, error) {
+	resolution := options.Resolution
+	if resolution <= 0 {
+		resolution = DefaultResolution
+	}
+	snapDistance := options.SnapDistance
+	if snapDistance <= 0 {
+		snapDistance = DefaultSnapDistance
+	}
+	builder := parser.NewBuilderWithOptions(options.Parse)
+
+	var collection struct {
+		Type     string           `json:"type"`
+		Features []geoJSONFeature `json:"features"`
+	}
+	if err := json.Unmarshal(data, &collection); err != nil || collection.Type != "FeatureCollection" {
+		if err == nil {
+			err = fmt.Errorf("type is %q, not \"FeatureCollection\"", collection.Type)
+		}
+		return nil, parser.Diagnostics{{
+			Severity: parser.SeverityError,
+			Code:     parser.CodeInvalidJSON,
+			Message:  fmt.Sprintf("invalid GeoJSON: %s", err),
+		}}
+	}
+
+	positions := featurePositions(data)
+	position := func(feature int) parser.Position {
+		if feature < len(positions) {
+			return positions[feature]
+		}
+		return parser.Position{}
+	}
+
+	var stopPoints []geoPoint
+	var stopFeatures []int
+	var stopCapacities []int
+	var lines []geoJSONLine
+
+	for i, feature := range collection.Features {
+		if feature.Geometry == nil {
+			continue
+		}
+		switch feature.Geometry.Type {
+		case "Point":
+			var coordinates []float64
+			if json.Unmarshal(feature.Geometry.Coordinates, &coordinates) != nil || len(coordinates) < 2 {
+				builder.Errorf(position(i), parser.CodeInvalidX, "feature %d: invalid Point coordinates", i)
+				continue
+			}
+			capacity := 0
+			if raw, exists := feature.Properties["capacity"]; exists {
+				if json.Unmarshal(raw, &capacity) != nil || capacity <= 0 {
+					builder.Errorf(position(i), parser.CodeInvalidCapacity, "feature %d: invalid capacity", i)
+					continue
+				}
+			}
+			stopPoints = append(stopPoints, geoPoint{Lon: coordinates[0], Lat: coordinates[1]})
+			stopFeatures = append(stopFeatures, i)
+			stopCapacities = append(stopCapacities, capacity)
+		case "LineString", "MultiLineString":
+			var parts [][][]float64
+			var err error
+			if feature.Geometry.Type == "LineString" {
+				var part [][]float64
+				err = json.Unmarshal(feature.Geometry.Coordinates, &part)
+				parts = [][][]float64{part}
+			} else {
+				err = json.Unmarshal(feature.Geometry.Coordinates, &parts)
+			}
+			if err != nil {
+				builder.Errorf(position(i), parser.CodeInvalidConnection, "feature %d: invalid %s coordinates", i, feature.Geometry.Type)
+				continue
+			}
+
+			var directed bool
+			if raw, exists := feature.Properties["oneway"]; exists {
+				json.Unmarshal(raw, &directed)
+			}
+			tracks := 0
+			if raw, exists := feature.Properties["tracks"]; exists {
+				if json.Unmarshal(raw, &tracks) != nil || tracks <= 0 {
+					builder.Errorf(position(i), parser.CodeInvalidTrackCapacity, "feature %d: invalid tracks", i)
+					continue
+				}
+			}
+
+			for _, part := range parts {
+				line := geoJSONLine{directed: directed, tracks: tracks, pos: position(i)}
+				for _, coordinates := range part {
+					if len(coordinates) >= 2 {
+						line.points = append(line.points, geoPoint{Lon: coordinates[0], Lat: coordinates[1]})
+					}
+				}
+				if len(line.points) >= 2 {
+					lines = append(lines, line)
+				}
+			}
+		}
+	}
+
+	stationNames := newNames()
+	names := make([]string, len(stopPoints))
+	for i, cell := range project(stopPoints, resolution) {
+		feature := collection.Features[stopFeatures[i]]
+		var name string
+		if raw, exists := feature.Properties["name"]; exists {
+			json.Unmarshal(raw, &name)
+		}
+		if name == "" {
+			name = fmt.Sprintf("stop_%d", stopFeatures[i])
+		}
+		names[i] = stationNames.sanitize(name)
+		builder.AddStation(parser.StationEntry{
+			Station: place(network.Station{Name: names[i], Capacity: stopCapacities[i]}, stopPoints[i], cell, options.Geographic),
+			Pos:     position(stopFeatures[i]),
+		})
+	}
+
+	// Lines that share track between the same stops make one connection.
+	// A two-way line takes over one-way tracks between its stops, one-way
+	// lines in opposite directions stay two one-way tracks.
+	var tracks []*geoJSONTrack
+	connected := make(map[[2]string]*geoJSONTrack)
+	for _, line := range lines {
+		var stops []lineStop
+		for i, point := range stopPoints {
+			if along, distance := snap(point, line.points); distance <= snapDistance {
+				stops = append(stops, lineStop{stop: i, along: along})
+			}
+		}
+		sort.SliceStable(stops, func(i, j int) bool { return stops[i].along < stops[j].along })
+
+		for i := 1; i < len(stops); i++ {
+			from, to := names[stops[i-1].stop], names[stops[i].stop]
+			if from == to {
+				continue
+			}
+			forward, backward := connected[[2]string{from, to}], connected[[2]string{to, from}]
+			if line.directed {
+				if forward == nil {
+					track := &geoJSONTrack{entry: parser.TrackEntry{From: from, To: to, Directed: true, Capacity: line.tracks, Pos: line.pos}}
+					tracks = append(tracks, track)
+					connected[[2]string{from, to}] = track
+				}
+				continue
+			}
+			if forward != nil && !forward.entry.Directed {
+				continue
+			}
+			track := forward
+			if track == nil {
+				track = backward
+			} else if backward != nil {
+				backward.dropped = true
+			}
+			if track == nil {
+				track = &geoJSONTrack{entry: parser.TrackEntry{From: from, To: to, Capacity: line.tracks, Pos: line.pos}}
+				tracks = append(tracks, track)
+			}
+			track.entry.Directed = false
+			track.entry.Capacity = max(track.entry.Capacity, line.tracks)
+			connected[[2]string{from, to}] = track
+			connected[[2]string{to, from}] = track
+		}
+	}
+	for _, track := range tracks {
+		if !track.dropped {
+			builder.AddConnection(track.entry)
+		}
+	}
+
+	builder.SetMetadata(network.Metadata{Coordinates: coordinates(options.Geographic)})
+	return builder.Build()
+}
+
+// featurePositions returns where each feature of the collection starts.
+// It returns nil when the document does not hold a list of features.
+func featurePositions(data []byte) []parser.Position {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil
+	}
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return nil
+		}
+		if key != "features" {
+			var value json.RawMessage
+			if decoder.Decode(&value) != nil {
+				return nil
+			}
+			continue
+		}
+		if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+			return nil
+		}
+		var positions []parser.Position
+		for decoder.More() {
+			offset := int(decoder.InputOffset())
+			for offset < len(data) && bytes.IndexByte([]byte(" \t\r\n,"), data[offset]) >= 0 {
+				offset++
+			}
+			before := data[:offset]
+			positions = append(positions, parser.Position{
+				Line: bytes.Count(before, []byte("\n")) + 1,
+				Col:  offset - bytes.LastIndexByte(before, '\n'),
+			})
+			var feature json.RawMessage
+			if decoder.Decode(&feature) != nil {
+				return nil
+			}
+		}
+		return positions
+	}
+	return nil
+}
+
+// snap finds the point of the line closest to point. It returns how far
+// along the line that point is and how far it is from point, in metres.
+func snap(point geoPoint, line []geoPoint) (float64, float64) {
+	bestAlong, bestDistance := 0.0, math.Inf(1)
+	along := 0.0
+	for i := 1; i < len(line); i++ {
+		// Work in metres on a plane around point, which is exact
+		// enough over the length of a snap.
+		ax, ay := metres(point, line[i-1])
+		bx, by := metres(point, line[i])
+		dx, dy := bx-ax, by-ay
+		length := math.Hypot(dx, dy)
+
+		t := 0.0
+		if length > 0 {
+			t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/(length*length)))
+		}
+		distance := math.Hypot(ax+t*dx, ay+t*dy)
+		if distance < bestDistance {
+			bestAlong, bestDistance = along+t*length, distance
+		}
+		along += length
+	}
+	return bestAlong, bestDistance
+}
+
+// metres returns where p is from origin, east and north in metres.
+func metres(origin, p geoPoint) (float64, float64) {
+	metresPerDegree := network.EarthRadius * math.Pi / 180
+	x := (p.Lon - origin.Lon) * math.Cos(origin.Lat*math.Pi/180) * metresPerDegree
+	y := (p.Lat - origin.Lat) * metresPerDegree
+	return x, y
+}
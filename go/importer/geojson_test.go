@@ -0,0 +1,89 @@
+package importer
+
+import (
+	"reflect"
+	"stations/go/parser"
+	"testing"
+)
+
+// geoJSON returns a FeatureCollection of two stops, a and b, followed by
+// the given features, one per line.
+func geoJSON(features ...string) string {
+	text := `{"type": "FeatureCollection", "features": [
+{"type": "Feature", "properties": {"name": "a"}, "geometry": {"type": "Point", "coordinates": [24.90, 60.10]}},
+{"type": "Feature", "properties": {"name": "b"}, "geometry": {"type": "Point", "coordinates": [24.91, 60.10]}}`
+	for _, feature := range features {
+		text += ",\n" + feature
+	}
+	return text + "\n]}"
+}
+
+// geoJSONLineFeature returns a line feature from a to b, or from b to a when
+// reversed, with the given extra properties.
+func geoJSONLineFeature(reversed bool, properties string) string {
+	coordinates := "[[24.90, 60.10], [24.91, 60.10]]"
+	if reversed {
+		coordinates = "[[24.91, 60.10], [24.90, 60.10]]"
+	}
+	return `{"type": "Feature", "properties": {` + properties + `}, "geometry": {"type": "LineString", "coordinates": ` + coordinates + `}}`
+}
+
+func TestGeoJSONSharedTrack(t *testing.T) {
+	type track struct {
+		from, to string
+		directed bool
+		capacity int
+	}
+	tests := []struct {
+		name     string
+		features []string
+		want     []track
+	}{
+		{"one-way then two-way", []string{
+			geoJSONLineFeature(false, `"oneway": true`),
+			geoJSONLineFeature(false, ``),
+		}, []track{{"a", "b", false, 0}}},
+		{"two-way then one-way back", []string{
+			geoJSONLineFeature(false, `"tracks": 2`),
+			geoJSONLineFeature(true, `"oneway": true`),
+		}, []track{{"a", "b", false, 2}}},
+		{"one-way both ways", []string{
+			geoJSONLineFeature(false, `"oneway": true`),
+			geoJSONLineFeature(true, `"oneway": true`),
+		}, []track{{"a", "b", true, 0}, {"b", "a", true, 0}}},
+		{"one-way both ways then two-way", []string{
+			geoJSONLineFeature(false, `"oneway": true`),
+			geoJSONLineFeature(true, `"oneway": true, "tracks": 3`),
+			geoJSONLineFeature(true, ``),
+		}, []track{{"b", "a", false, 3}}},
+	}
+	for _, test := range tests {
+		net, err := ParseGeoJSON([]byte(geoJSON(test.features...)), GeoJSONOptions{})
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		var got []track
+		for _, connection := range net.Connections {
+			got = append(got, track{connection.Start.Name, connection.End.Name, connection.Directed, connection.Capacity})
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestGeoJSONDiagnosticPositions(t *testing.T) {
+	_, err := ParseGeoJSON([]byte(geoJSON(
+		geoJSONLineFeature(false, ``),
+		`  {"type": "Feature", "properties": {"capacity": 0}, "geometry": {"type": "Point", "coordinates": [24.95, 60.10]}}`,
+	)), GeoJSONOptions{})
+	diagnostics, ok := err.(parser.Diagnostics)
+	if !ok || len(diagnostics) != 1 {
+		t.Fatalf("got %v, want one diagnostic", err)
+	}
+	want := parser.Position{Line: 5, Col: 3}
+	if diagnostic := diagnostics[0]; diagnostic.Code != parser.CodeInvalidCapacity || diagnostic.Pos != want {
+		t.Errorf("got %v, want %s at %s", diagnostic, parser.CodeInvalidCapacity, want)
+	}
+}
@@ -0,0 +1,407 @@
+package importer
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	network "stations/go/network/dijkstra"
+	"stations/go/parser"
+	"strconv"
+	"strings"
+)
+
+/*
+GTFS feeds are read from stops.txt, trips.txt and stop_times.txt:
+
+  - every stop served by a trip becomes a station, with stops that have a
+    parent_station merged into their parent,
+  - every two stations a trip calls at one after the other are connected,
+    one-way unless some trip also runs the other way,
+  - the travel time of a connection is the shortest time any trip takes
+    between the two stations, in whole minutes.
+*/
+
+// GTFSOptions changes how ReadGTFS turns a feed into a network.
+type GTFSOptions struct {
+	// Resolution is the size of one map grid unit in metres,
+	// DefaultResolution when 0.
+	Resolution float64
+	// Geographic keeps the latitude and longitude of stops as station
+	// coordinates instead of projecting them onto a grid.
+	Geographic bool
+	// Parse are the rules the network is checked by.
+	Parse parser.ParseOptions
+}
+
+// ReadGTFS reads the GTFS feed in the directory or zip file at path and
+// builds a network from it. The network is checked by the same rules as a
+// map file and its diagnostics point at the rows of the feed's files.
+func ReadGTFS(path string, options GTFSOptions) (*network.Network, error) {
+	feed, closeFeed, err := openFeed(path)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFeed()
+
+	net, err := buildGTFS(feed, options)
+	var diagnostics parser.Diagnostics
+	if errors.As(err, &diagnostics) {
+		for i := range diagnostics {
+			if diagnostics[i].Pos.File != "" {
+				diagnostics[i].Pos.File = filepath.Join(path, diagnostics[i].Pos.File)
+			}
+		}
+		return nil, diagnostics
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Locations point at the rows of the feed's files, like diagnostics.
+	for name, location := range net.Locations.Stations {
+		location.File = filepath.Join(path, location.File)
+		net.Locations.Stations[name] = location
+	}
+	for key, location := range net.Locations.Connections {
+		location.File = filepath.Join(path, location.File)
+		net.Locations.Connections[key] = location
+	}
+	net.Metadata.Source = path
+	return net, nil
+}
+
+// IsGTFS reports whether path is a directory or zip file holding a GTFS feed.
+func IsGTFS(path string) bool {
+	feed, closeFeed, err := openFeed(path)
+	if err != nil {
+		return false
+	}
+	defer closeFeed()
+	_, err = fs.Stat(feed, "stops.txt")
+	return err == nil
+}
+
+// openFeed opens a feed directory or zip file as a file system.
+func openFeed(path string) (fs.FS, func() error, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		return os.DirFS(path), func() error { return nil }, nil
+	}
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return archive, archive.Close, nil
+}
+
+// gtfsStop is a row of stops.txt.
+type gtfsStop struct {
+	name     string
+	point    geoPoint
+	parent   string
+	pos      parser.Position
+	hasPoint bool
+}
+
+// gtfsStopTime is a row of stop_times.txt.
+type gtfsStopTime struct {
+	stop      string
+	sequence  int
+	arrival   int
+	departure int
+	pos       parser.Position
+}
+
+// gtfsLeg is a trip going from one station straight to the next.
+type gtfsLeg struct {
+	from, to string
+	// time is the shortest travel time in minutes, 0 when unknown.
+	time int
+	pos  parser.Position
+}
+
+func buildGTFS(feed fs.FS, options GTFSOptions) (*network.Network, error) {
+	resolution := options.Resolution
+	if resolution <= 0 {
+		resolution = DefaultResolution
+	}
+	builder := parser.NewBuilderWithOptions(options.Parse)
+
+	stops, err := readStops(feed, builder)
+	if err != nil {
+		return nil, err
+	}
+	trips, err := readTrips(feed)
+	if err != nil {
+		return nil, err
+	}
+	stopTimes, err := readStopTimes(feed, builder)
+	if err != nil {
+		return nil, err
+	}
+
+	// station returns the stop that stands for a stop on the map: its
+	// parent station if it has one.
+	station := func(stopID string) string {
+		if stop, exists := stops[stopID]; exists && stop.parent != "" {
+			if _, exists := stops[stop.parent]; exists {
+				return stop.parent
+			}
+		}
+		return stopID
+	}
+
+	legs := make(map[[2]string]*gtfsLeg)
+	var legOrder [][2]string
+	var served []string
+	isServed := make(map[string]bool)
+	serve := func(id string) {
+		if !isServed[id] {
+			isServed[id] = true
+			served = append(served, id)
+		}
+	}
+
+	tripIDs := make([]string, 0, len(stopTimes))
+	for tripID := range stopTimes {
+		tripIDs = append(tripIDs, tripID)
+	}
+	sort.Strings(tripIDs)
+
+	for _, tripID := range tripIDs {
+		calls := stopTimes[tripID]
+		if !trips[tripID] {
+			builder.Errorf(calls[0].pos, parser.CodeUnknownTrip, "stop time for unknown trip %s", tripID)
+			continue
+		}
+		sort.SliceStable(calls, func(i, j int) bool { return calls[i].sequence < calls[j].sequence })
+
+		for i, call := range calls {
+			if _, exists := stops[call.stop]; !exists {
+				builder.Errorf(call.pos, parser.CodeUnknownStop, "stop time for unknown stop %s", call.stop)
+				continue
+			}
+			serve(station(call.stop))
+			if i == 0 {
+				continue
+			}
+			previous := calls[i-1]
+			from, to := station(previous.stop), station(call.stop)
+			if _, exists := stops[previous.stop]; !exists || from == to {
+				continue
+			}
+
+			minutes := 0
+			if previous.departure >= 0 && call.arrival > previous.departure {
+				minutes = int(math.Ceil(float64(call.arrival-previous.departure) / 60))
+			}
+			key := [2]string{from, to}
+			leg, exists := legs[key]
+			if !exists {
+				leg = &gtfsLeg{from: from, to: to, time: minutes, pos: call.pos}
+				legs[key] = leg
+				legOrder = append(legOrder, key)
+			} else if minutes > 0 && (leg.time == 0 || minutes < leg.time) {
+				leg.time = minutes
+			}
+		}
+	}
+
+	var points []geoPoint
+	var located []string
+	for _, id := range served {
+		stop := stops[id]
+		if !stop.hasPoint {
+			builder.Errorf(stop.pos, parser.CodeMissingStopLocation, "stop %s has no stop_lat and stop_lon", id)
+			continue
+		}
+		points = append(points, stop.point)
+		located = append(located, id)
+	}
+
+	stationNames := newNames()
+	nameOf := make(map[string]string)
+	for i, cell := range project(points, resolution) {
+		id := located[i]
+		stop := stops[id]
+		name := stationNames.sanitize(stop.name)
+		if stop.name == "" {
+			name = stationNames.sanitize(id)
+		}
+		nameOf[id] = name
+		builder.AddStation(parser.StationEntry{
+			Station: place(network.Station{Name: name}, points[i], cell, options.Geographic),
+			Pos:     stop.pos,
+		})
+	}
+
+	for _, key := range legOrder {
+		leg := legs[key]
+		reverse, twoWay := legs[[2]string{leg.to, leg.from}]
+		if twoWay && leg.from > leg.to {
+			// Written once, by the leg with the first station first.
+			continue
+		}
+		time := leg.time
+		if twoWay && reverse.time > 0 && (time == 0 || reverse.time < time) {
+			time = reverse.time
+		}
+		from, to := nameOf[leg.from], nameOf[leg.to]
+		if from == "" || to == "" {
+			continue
+		}
+		builder.AddConnection(parser.TrackEntry{
+			From:     from,
+			To:       to,
+			Time:     time,
+			Directed: !twoWay,
+			Pos:      leg.pos,
+		})
+	}
+
+	builder.SetMetadata(network.Metadata{Coordinates: coordinates(options.Geographic)})
+	return builder.Build()
+}
+
+// readStops reads stops.txt.
+func readStops(feed fs.FS, builder *parser.Builder) (map[string]gtfsStop, error) {
+	stops := make(map[string]gtfsStop)
+	err := readTable(feed, "stops.txt", []string{"stop_id"}, func(row gtfsRow) {
+		id := row.get("stop_id")
+		stop := gtfsStop{name: row.get("stop_name"), parent: row.get("parent_station"), pos: row.pos}
+
+		lat, latErr := strconv.ParseFloat(row.get("stop_lat"), 64)
+		lon, lonErr := strconv.ParseFloat(row.get("stop_lon"), 64)
+		if latErr == nil && lonErr == nil {
+			stop.point = geoPoint{Lat: lat, Lon: lon}
+			stop.hasPoint = true
+		}
+
+		if _, exists := stops[id]; exists {
+			builder.Errorf(row.pos, parser.CodeDuplicateStop, "duplicate stop_id: %s", id)
+			return
+		}
+		stops[id] = stop
+	})
+	return stops, err
+}
+
+// readTrips reads the trip ids of trips.txt.
+func readTrips(feed fs.FS) (map[string]bool, error) {
+	trips := make(map[string]bool)
+	err := readTable(feed, "trips.txt", []string{"trip_id"}, func(row gtfsRow) {
+		trips[row.get("trip_id")] = true
+	})
+	return trips, err
+}
+
+// readStopTimes reads stop_times.txt, grouped by trip.
+func readStopTimes(feed fs.FS, builder *parser.Builder) (map[string][]gtfsStopTime, error) {
+	stopTimes := make(map[string][]gtfsStopTime)
+	err := readTable(feed, "stop_times.txt", []string{"trip_id", "stop_id", "stop_sequence"}, func(row gtfsRow) {
+		sequence, err := strconv.Atoi(row.get("stop_sequence"))
+		if err != nil {
+			builder.Errorf(row.pos, parser.CodeInvalidStopSequence, "invalid stop_sequence: %s", row.get("stop_sequence"))
+			return
+		}
+		arrival := gtfsTime(row.get("arrival_time"))
+		departure := gtfsTime(row.get("departure_time"))
+		if departure < 0 {
+			departure = arrival
+		}
+		if arrival < 0 {
+			arrival = departure
+		}
+		tripID := row.get("trip_id")
+		stopTimes[tripID] = append(stopTimes[tripID], gtfsStopTime{
+			stop:      row.get("stop_id"),
+			sequence:  sequence,
+			arrival:   arrival,
+			departure: departure,
+			pos:       row.pos,
+		})
+	})
+	return stopTimes, err
+}
+
+// gtfsTime turns a GTFS time of the form HH:MM:SS into seconds. Hours may go
+// past 24 for trips that run after midnight. It returns -1 when the time is
+// missing or invalid.
+func gtfsTime(text string) int {
+	parts := strings.Split(strings.TrimSpace(text), ":")
+	if len(parts) != 3 {
+		return -1
+	}
+	seconds := 0
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return -1
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds
+}
+
+// gtfsRow is a row of a GTFS file, with its fields looked up by column name.
+type gtfsRow struct {
+	columns map[string]int
+	record  []string
+	pos     parser.Position
+}
+
+func (r gtfsRow) get(column string) string {
+	if i, exists := r.columns[column]; exists && i < len(r.record) {
+		return strings.TrimSpace(r.record[i])
+	}
+	return ""
+}
+
+// readTable reads the CSV file name from the feed, calling row for every
+// row after the header. It fails when the file or a required column is missing.
+func readTable(feed fs.FS, name string, required []string, row func(gtfsRow)) error {
+	file, err := feed.Open(name)
+	if err != nil {
+		return fmt.Errorf("GTFS feed has no %s", name)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	columns := make(map[string]int)
+	for i, column := range header {
+		// Feeds written on Windows often start with a byte order mark.
+		columns[strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))] = i
+	}
+	for _, column := range required {
+		if _, exists := columns[column]; !exists {
+			return fmt.Errorf("%s has no %s column", name, column)
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		line, _ := reader.FieldPos(0)
+		row(gtfsRow{columns: columns, record: record, pos: parser.Position{File: name, Line: line}})
+	}
+}
@@ -0,0 +1,101 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"stations/go/parser"
+	"testing"
+)
+
+// writeFeed writes a GTFS feed directory with the given files.
+func writeFeed(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+const (
+	gtfsStops = `stop_id,stop_name,stop_lat,stop_lon,parent_station
+hki,Helsinki,60.1719,24.9414,
+hki1,Helsinki platform 1,60.1720,24.9415,hki
+psl,Pasila,60.1986,24.9335,
+tkl,Tikkurila,60.2925,25.0440,
+`
+	gtfsTrips = `route_id,service_id,trip_id
+r,s,north
+r,s,south
+`
+	gtfsStopTimes = `trip_id,arrival_time,departure_time,stop_id,stop_sequence
+north,08:00:00,08:00:00,hki1,1
+north,08:05:00,08:06:00,psl,2
+north,08:15:00,08:15:00,tkl,3
+south,09:00:00,09:00:00,psl,1
+south,09:04:30,09:04:30,hki,2
+`
+)
+
+func TestReadGTFS(t *testing.T) {
+	net, err := ReadGTFS(writeFeed(t, map[string]string{
+		"stops.txt":      gtfsStops,
+		"trips.txt":      gtfsTrips,
+		"stop_times.txt": gtfsStopTimes,
+	}), GTFSOptions{})
+	if err != nil {
+		t.Fatalf("reading feed: %v", err)
+	}
+
+	type track struct {
+		from, to string
+		time     int
+		directed bool
+	}
+	var got []track
+	for _, connection := range net.Connections {
+		got = append(got, track{connection.Start.Name, connection.End.Name, connection.Time, connection.Directed})
+	}
+	want := []track{
+		// The platform is merged into Helsinki, and the trip back takes
+		// 4.5 minutes, rounded up.
+		{"helsinki", "pasila", 5, false},
+		{"pasila", "tikkurila", 9, true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got connections %v, want %v", got, want)
+	}
+}
+
+func TestGTFSDiagnostics(t *testing.T) {
+	tests := []struct {
+		name      string
+		stops     string
+		stopTimes string
+		want      string
+	}{
+		{"duplicate stop", gtfsStops + "psl,Pasila again,60.2,24.9,\n", gtfsStopTimes, parser.CodeDuplicateStop},
+		{"unknown stop", gtfsStops, gtfsStopTimes + "south,09:10:00,09:10:00,nowhere,3\n", parser.CodeUnknownStop},
+		{"unknown trip", gtfsStops, gtfsStopTimes + "west,10:00:00,10:00:00,psl,1\n", parser.CodeUnknownTrip},
+		{"invalid stop_sequence", gtfsStops, gtfsStopTimes + "south,09:10:00,09:10:00,tkl,third\n", parser.CodeInvalidStopSequence},
+		{"missing location", gtfsStops + "krv,Kerava,,,\n", gtfsStopTimes + "north,08:30:00,08:30:00,krv,4\n", parser.CodeMissingStopLocation},
+	}
+	for _, test := range tests {
+		_, err := ReadGTFS(writeFeed(t, map[string]string{
+			"stops.txt":      test.stops,
+			"trips.txt":      gtfsTrips,
+			"stop_times.txt": test.stopTimes,
+		}), GTFSOptions{})
+		diagnostics, ok := err.(parser.Diagnostics)
+		if !ok {
+			t.Errorf("%s: got %v, want diagnostics", test.name, err)
+			continue
+		}
+		if len(diagnostics) != 1 || diagnostics[0].Code != test.want || diagnostics[0].Pos.Line == 0 {
+			t.Errorf("%s: got %v, want one %s at a row of the feed", test.name, diagnostics, test.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package importer
+
+import (
+	"math"
+	network "stations/go/network/dijkstra"
+	"strconv"
+	"strings"
+)
+
+// DefaultResolution is the size of one map grid unit in metres.
+const DefaultResolution = 100.0
+
+// geoPoint is a point given by latitude and longitude in degrees.
+type geoPoint struct {
+	Lat, Lon float64
+}
+
+// place puts a station at point in geographic mode, and at cell of the
+// projected grid otherwise.
+func place(station network.Station, point geoPoint, cell [2]int, geographic bool) network.Station {
+	if geographic {
+		station.Lat, station.Lon, station.Geographic = point.Lat, point.Lon, true
+		return station
+	}
+	station.X, station.Y = cell[0], cell[1]
+	return station
+}
+
+// coordinates returns the coordinates header of an imported map.
+func coordinates(geographic bool) string {
+	if geographic {
+		return network.CoordinatesGeographic
+	}
+	return ""
+}
+
+// project turns geographic points into the non-negative integer grid of a
+// map, with one grid unit per resolution metres, by the projection of
+// network.GeographicProjection. Points that would land on a cell that is
+// already taken are moved to the nearest free cell, since stations cannot
+// share coordinates.
+func project(points []geoPoint, resolution float64) [][2]int {
+	stations := make([]network.Station, len(points))
+	for i, point := range points {
+		stations[i] = network.Station{Lat: point.Lat, Lon: point.Lon, Geographic: true}
+	}
+	metres := network.GeographicProjection(stations)
+
+	grid := make([][2]int, len(points))
+	taken := make(map[[2]int]bool)
+	for i, station := range stations {
+		x, y := metres(station)
+		cell := nearestFreeCell([2]int{int(math.Round(x / resolution)), int(math.Round(y / resolution))}, taken)
+		taken[cell] = true
+		grid[i] = cell
+	}
+	return grid
+}
+
+// nearestFreeCell returns cell if it is free, or else the closest free cell
+// around it with non-negative coordinates, searching ring by ring.
+func nearestFreeCell(cell [2]int, taken map[[2]int]bool) [2]int {
+	if !taken[cell] {
+		return cell
+	}
+	for ring := 1; ; ring++ {
+		for dx := -ring; dx <= ring; dx++ {
+			for dy := -ring; dy <= ring; dy++ {
+				if max(abs(dx), abs(dy)) != ring {
+					continue
+				}
+				candidate := [2]int{cell[0] + dx, cell[1] + dy}
+				if candidate[0] >= 0 && candidate[1] >= 0 && !taken[candidate] {
+					return candidate
+				}
+			}
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// names hands out station names that follow the map rule ^[a-z0-9_]+$ and
+// are unique within one import.
+type names struct {
+	used map[string]bool
+}
+
+func newNames() *names {
+	return &names{used: make(map[string]bool)}
+}
+
+// sanitize turns a free-text name into a unique station name: lower case,
+// every run of other characters replaced by one underscore, and a number
+// appended when the name is already taken.
+func (n *names) sanitize(text string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(text) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			underscore = false
+		} else if !underscore && b.Len() > 0 {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	name := strings.TrimSuffix(b.String(), "_")
+	if name == "" {
+		name = "station"
+	}
+
+	unique := name
+	for i := 2; n.used[unique]; i++ {
+		unique = name + "_" + strconv.Itoa(i)
+	}
+	n.used[unique] = true
+	return unique
+}
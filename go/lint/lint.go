@@ -0,0 +1,333 @@
+package lint
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	network "stations/go/network/dijkstra"
+	"stations/go/parser"
+	"strings"
+)
+
+// Stable codes for every finding of the linter.
+const (
+	CodeIsolatedStation      = "L001"
+	CodeDisconnectedStations = "L002"
+	CodeDeadEndSpur          = "L003"
+	CodeCrossingTracks       = "L004"
+	CodeLongConnection       = "L005"
+)
+
+// LongConnectionFactor is how many times longer than the median connection
+// a connection has to be to be unusually long.
+const LongConnectionFactor = 5
+
+// maxListed is how many station names a finding lists before "...".
+const maxListed = 5
+
+// Lint checks a network for problems that make a valid map hard to use:
+//
+//   - stations without any connection, as errors,
+//   - groups of stations that cannot be reached from the rest, as warnings,
+//   - tracks that cross where there is no station, as warnings,
+//   - connections much longer than the others, as warnings,
+//   - dead-end spurs, as information.
+//
+// The findings come in that order, name the stations they are about and
+// point at where the map defines the first of them.
+func Lint(net *network.Network) parser.Diagnostics {
+	var findings parser.Diagnostics
+	neighbours := undirectedNeighbours(net)
+
+	isolated := make(map[string]bool)
+	for _, name := range net.StationNames() {
+		if len(neighbours[name]) == 0 {
+			isolated[name] = true
+			findings = append(findings, finding(stationAt(net, name), parser.SeverityError, CodeIsolatedStation, "station %s has no connections", name))
+		}
+	}
+
+	findings = append(findings, disconnected(net, neighbours, isolated)...)
+	findings = append(findings, crossings(net)...)
+	findings = append(findings, longConnections(net)...)
+	findings = append(findings, spurs(net, neighbours)...)
+	return findings
+}
+
+// undirectedNeighbours returns the stations each station shares a track
+// with, whichever way the track can be travelled, in order.
+func undirectedNeighbours(net *network.Network) map[string][]string {
+	seen := make(map[[2]string]bool)
+	neighbours := make(map[string][]string)
+	for _, connection := range net.Connections {
+		from, to := connection.Start.Name, connection.End.Name
+		if !seen[[2]string{from, to}] {
+			seen[[2]string{from, to}] = true
+			seen[[2]string{to, from}] = true
+			neighbours[from] = append(neighbours[from], to)
+			neighbours[to] = append(neighbours[to], from)
+		}
+	}
+	for _, list := range neighbours {
+		sort.Strings(list)
+	}
+	return neighbours
+}
+
+// disconnected reports every group of connected stations but the largest,
+// leaving out isolated stations, which are reported on their own.
+func disconnected(net *network.Network, neighbours map[string][]string, isolated map[string]bool) parser.Diagnostics {
+	var components [][]string
+	visited := make(map[string]bool)
+	for _, name := range net.StationNames() {
+		if visited[name] || isolated[name] {
+			continue
+		}
+		visited[name] = true
+		component := []string{name}
+		for queue := []string{name}; len(queue) > 0; queue = queue[1:] {
+			for _, neighbour := range neighbours[queue[0]] {
+				if !visited[neighbour] {
+					visited[neighbour] = true
+					component = append(component, neighbour)
+					queue = append(queue, neighbour)
+				}
+			}
+		}
+		sort.Strings(component)
+		components = append(components, component)
+	}
+	if len(components) < 2 {
+		return nil
+	}
+
+	// The largest group is the map, the others are cut off from it.
+	sort.SliceStable(components, func(i, j int) bool { return len(components[i]) > len(components[j]) })
+	var findings parser.Diagnostics
+	for _, component := range components[1:] {
+		findings = append(findings, finding(stationAt(net, component[0]), parser.SeverityWarning, CodeDisconnectedStations, "%d stations cannot be reached from the rest of the map: %s", len(component), list(component)))
+	}
+	return findings
+}
+
+// spurs reports lines of stations that lead from a junction to a station
+// with a single neighbour, other than the default start and end stations.
+// A group of stations that is one line from end to end has no junction and
+// no spurs.
+func spurs(net *network.Network, neighbours map[string][]string) parser.Diagnostics {
+	var findings parser.Diagnostics
+	for _, end := range net.StationNames() {
+		if len(neighbours[end]) != 1 || end == net.Metadata.DefaultStart || end == net.Metadata.DefaultEnd {
+			continue
+		}
+		// Walk back from the dead end until the line meets a junction.
+		spur := []string{end}
+		previous, current := end, neighbours[end][0]
+		for len(neighbours[current]) == 2 {
+			spur = append(spur, current)
+			next := neighbours[current][0]
+			if next == previous {
+				next = neighbours[current][1]
+			}
+			previous, current = current, next
+		}
+		if len(neighbours[current]) < 3 {
+			continue
+		}
+		// List the spur from the junction outwards.
+		for i, j := 0, len(spur)-1; i < j; i, j = i+1, j-1 {
+			spur[i], spur[j] = spur[j], spur[i]
+		}
+		findings = append(findings, finding(stationAt(net, end), parser.SeverityInfo, CodeDeadEndSpur, "dead-end spur from %s: %s", current, list(spur)))
+	}
+	return findings
+}
+
+// segment is a connection drawn as a straight line between its stations.
+type segment struct {
+	from, to               string
+	x1, y1, x2, y2         float64
+	minX, maxX, minY, maxY float64
+	pos                    parser.Position
+}
+
+func (s segment) String() string {
+	return s.from + "-" + s.to
+}
+
+// crossings reports pairs of tracks that cross each other away from their
+// stations.
+func crossings(net *network.Network) parser.Diagnostics {
+	project := net.Projection()
+	seen := make(map[[2]string]bool)
+	var segments []segment
+	for _, connection := range net.Connections {
+		from, to := connection.Start.Name, connection.End.Name
+		if from > to {
+			from, to = to, from
+		}
+		if seen[[2]string{from, to}] {
+			continue
+		}
+		seen[[2]string{from, to}] = true
+		x1, y1 := project(net.Stations[from])
+		x2, y2 := project(net.Stations[to])
+		segments = append(segments, segment{
+			from: from, to: to,
+			x1: x1, y1: y1, x2: x2, y2: y2,
+			minX: math.Min(x1, x2), maxX: math.Max(x1, x2),
+			minY: math.Min(y1, y2), maxY: math.Max(y1, y2),
+			pos: connectionAt(net, connection),
+		})
+	}
+
+	// Sweep from left to right, so that only tracks whose x ranges
+	// overlap are compared.
+	sort.Slice(segments, func(i, j int) bool {
+		if segments[i].minX != segments[j].minX {
+			return segments[i].minX < segments[j].minX
+		}
+		return segments[i].String() < segments[j].String()
+	})
+	// A crossing is reported at the track that comes first in the map.
+	type crossing struct {
+		tracks [2]string
+		pos    parser.Position
+	}
+	var pairs []crossing
+	for i, a := range segments {
+		for _, b := range segments[i+1:] {
+			if b.minX > a.maxX {
+				break
+			}
+			if b.minY > a.maxY || b.maxY < a.minY {
+				continue
+			}
+			if a.from == b.from || a.from == b.to || a.to == b.from || a.to == b.to {
+				continue
+			}
+			if cross(a, b) {
+				pair := crossing{tracks: [2]string{a.String(), b.String()}, pos: a.pos}
+				if pair.tracks[1] < pair.tracks[0] {
+					pair.tracks[0], pair.tracks[1] = pair.tracks[1], pair.tracks[0]
+				}
+				if before(b.pos, a.pos) {
+					pair.pos = b.pos
+				}
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].tracks[0] != pairs[j].tracks[0] {
+			return pairs[i].tracks[0] < pairs[j].tracks[0]
+		}
+		return pairs[i].tracks[1] < pairs[j].tracks[1]
+	})
+
+	var findings parser.Diagnostics
+	for _, pair := range pairs {
+		findings = append(findings, finding(pair.pos, parser.SeverityWarning, CodeCrossingTracks, "tracks %s and %s cross without a station", pair.tracks[0], pair.tracks[1]))
+	}
+	return findings
+}
+
+// cross reports whether two tracks without a station in common cross or
+// run along each other.
+func cross(a, b segment) bool {
+	d1 := orientation(b.x1, b.y1, b.x2, b.y2, a.x1, a.y1)
+	d2 := orientation(b.x1, b.y1, b.x2, b.y2, a.x2, a.y2)
+	d3 := orientation(a.x1, a.y1, a.x2, a.y2, b.x1, b.y1)
+	d4 := orientation(a.x1, a.y1, a.x2, a.y2, b.x2, b.y2)
+	if d1*d2 < 0 && d3*d4 < 0 {
+		return true
+	}
+	// Tracks on one line overlap when their ranges do.
+	if d1 == 0 && d2 == 0 && d3 == 0 && d4 == 0 {
+		return math.Max(a.minX, b.minX) < math.Min(a.maxX, b.maxX) ||
+			math.Max(a.minY, b.minY) < math.Min(a.maxY, b.maxY)
+	}
+	return false
+}
+
+// orientation returns which side of the line from (x1, y1) to (x2, y2) the
+// point (x, y) is on: 1 or -1, or 0 on the line.
+func orientation(x1, y1, x2, y2, x, y float64) float64 {
+	cross := (x2-x1)*(y-y1) - (y2-y1)*(x-x1)
+	switch {
+	case cross > 0:
+		return 1
+	case cross < 0:
+		return -1
+	}
+	return 0
+}
+
+// longConnections reports connections more than LongConnectionFactor times
+// as long as the median connection.
+func longConnections(net *network.Network) parser.Diagnostics {
+	if len(net.Connections) == 0 {
+		return nil
+	}
+	lengths := make([]float64, len(net.Connections))
+	for i, connection := range net.Connections {
+		lengths[i] = connection.Start.Distance(connection.End)
+	}
+	sorted := append([]float64(nil), lengths...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+	if median <= 0 {
+		return nil
+	}
+
+	unit := "units"
+	if net.Geographic() {
+		unit = "metres"
+	}
+	var findings parser.Diagnostics
+	for i, connection := range net.Connections {
+		if lengths[i] > LongConnectionFactor*median {
+			findings = append(findings, finding(connectionAt(net, connection), parser.SeverityWarning, CodeLongConnection, "connection %s-%s is %.0f %s long, %.1f times the median connection", connection.Start.Name, connection.End.Name, lengths[i], unit, lengths[i]/median))
+		}
+	}
+	return findings
+}
+
+// list joins station names, listing at most maxListed of them.
+func list(names []string) string {
+	if len(names) > maxListed {
+		return strings.Join(names[:maxListed], ", ") + ", ..."
+	}
+	return strings.Join(names, ", ")
+}
+
+// finding returns a finding at pos, which is empty for networks that were
+// not read from a map.
+func finding(pos parser.Position, severity parser.Severity, code, format string, args ...interface{}) parser.Diagnostic {
+	return parser.Diagnostic{Pos: pos, Severity: severity, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// stationAt returns where the map defines the station.
+func stationAt(net *network.Network, name string) parser.Position {
+	return position(net.Locations.Stations[name])
+}
+
+// connectionAt returns where the map defines the connection.
+func connectionAt(net *network.Network, connection network.Connection) parser.Position {
+	return position(net.Locations.Connections[[2]string{connection.Start.Name, connection.End.Name}])
+}
+
+func position(location network.Location) parser.Position {
+	return parser.Position{File: location.File, Line: location.Line, Col: location.Col}
+}
+
+// before reports whether a comes before b in the map, by file and line.
+func before(a, b parser.Position) bool {
+	if a.File != b.File {
+		return a.File < b.File
+	}
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Col < b.Col
+}
@@ -0,0 +1,56 @@
+package lint
+
+import (
+	"stations/go/parser"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	net, err := parser.ParseNetwork(strings.NewReader(`stations:
+a,0,0
+b,2,2
+c,0,2
+d,2,0
+e,1,5
+f,9,9
+g,20,20
+h,21,20
+i,200,200
+
+connections:
+a-b
+c-d
+a-c
+b-d
+b-e
+g-h
+h-i
+`))
+	if err != nil {
+		t.Fatalf("parsing map: %v", err)
+	}
+
+	type want struct {
+		code string
+		line int
+		text string
+	}
+	wants := []want{
+		{CodeIsolatedStation, 7, "station f has no connections"},
+		{CodeDisconnectedStations, 8, "3 stations cannot be reached from the rest of the map: g, h, i"},
+		{CodeCrossingTracks, 13, "tracks a-b and c-d cross without a station"},
+		{CodeLongConnection, 19, "connection h-i is"},
+		{CodeDeadEndSpur, 6, "dead-end spur from b: e"},
+	}
+	findings := Lint(net)
+	if len(findings) != len(wants) {
+		t.Fatalf("got %d findings, want %d:\n%v", len(findings), len(wants), findings)
+	}
+	for i, want := range wants {
+		got := findings[i]
+		if got.Code != want.code || got.Pos.Line != want.line || got.Pos.Col != 1 || !strings.HasPrefix(got.Message, want.text) {
+			t.Errorf("finding %d: got %v, want %s at line %d: %s", i, got, want.code, want.line, want.text)
+		}
+	}
+}
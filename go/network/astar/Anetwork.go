@@ -14,25 +14,15 @@ type Node struct {
 /*
 Here's an example to illustrate the relationship:
 
-    Station: Represents a point on the map (e.g., "StationA" at coordinates (1, 2)).
+    Station: Represents a point on the map (e.g., "StationA" at coordinates (1, 2)),
+	shared with the Dijkstra pathfinding as network.Station.
     Node: Represents the state of the algorithm's search process at "StationA", 
 	including the cost to get there, the priority for further exploration, the 
 	parent node, and possibly the time taken.
     Train: Represents an entity moving through the network of stations, potentially
-	 using the path found by the A* algorithm.
+	 using the path found by the A* algorithm, shared as network.Train.
 */
 
-type Station struct {
-	Name string
-	X    int
-	Y    int
-}
-
-type Graph struct {
-	Stations    map[string]Station
-	Connections map[string][]string
-}
-
 // Implement heap.Interface for PriorityQueue
 func (pq PriorityQueue) Len() int { return len(pq) }
 func (pq PriorityQueue) Less(i, j int) bool {
@@ -53,12 +43,6 @@ func (pq *PriorityQueue) Pop() interface{} {
 	return node
 }
 
-// Train represents a train with an ID and color.
-type Train struct {
-	ID    int
-	Color string
-}
-
 // Queue implementation for station queues with string support
 type StringQueue struct {
 	items []string
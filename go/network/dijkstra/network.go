@@ -1,10 +1,41 @@
 //network.go
 package network
 
-// Station represents a station with an X, Y coordinate.
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Station represents a station with an X, Y coordinate. Capacity is the
+// number of trains the station can hold at once, 0 when the map gives none.
+// A Geographic station is placed by Lat and Lon, in degrees, instead.
 type Station struct {
-	Name string
-	X, Y int
+	Name       string
+	X, Y       int
+	Capacity   int
+	Lat, Lon   float64
+	Geographic bool
+}
+
+// EarthRadius is the mean radius of the earth in metres.
+const EarthRadius = 6371000.0
+
+// Distance returns the straight distance between two stations: in grid
+// units between stations placed by X and Y, and in metres along the
+// surface of the earth between geographic stations.
+func (s Station) Distance(other Station) float64 {
+	if s.Geographic && other.Geographic {
+		lat1, lat2 := s.Lat*math.Pi/180, other.Lat*math.Pi/180
+		dLat := lat2 - lat1
+		dLon := (other.Lon - s.Lon) * math.Pi / 180
+		a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+		return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+	}
+	dx := float64(s.X - other.X)
+	dy := float64(s.Y - other.Y)
+	return math.Sqrt(dx*dx + dy*dy)
 }
 
 // Item represents an element in the priority queue with a value, priority, and index.
@@ -57,17 +88,256 @@ func (pq *PriorityQueue) Pop() interface{} {
 }
 
 // Connection represents a connection between two stations with a travel time.
+// A directed connection can only be travelled from Start to End. Capacity is
+// the number of parallel tracks, 0 when the map gives none.
 type Connection struct {
-	Start Station
-	End   Station
-	Time  int
+	Start    Station
+	End      Station
+	Time     int
+	Directed bool
+	Capacity int
+}
+
+// TrackCapacity returns how many trains can use the connection in one turn.
+// Connections without a capacity in the map are single track.
+func (c Connection) TrackCapacity() int {
+	if c.Capacity > 0 {
+		return c.Capacity
+	}
+	return 1
+}
+
+// TravelTime returns the travel time given for the connection in the map, or
+// the distance between its stations when the map did not give one.
+func (c Connection) TravelTime() int {
+	if c.Time > 0 {
+		return c.Time
+	}
+	return int(c.Start.Distance(c.End))
 }
 
 // Connections is a slice of Connection.
 type Connections []Connection
 
+// Metadata describes a network: where it was read from, which files it was
+// made of and what the header of its map says about it.
+type Metadata struct {
+	Source       string
+	Name         string
+	Author       string
+	Version      string
+	DefaultStart string
+	DefaultEnd   string
+	// Coordinates is CoordinatesGeographic for maps whose stations are
+	// placed by latitude and longitude.
+	Coordinates string
+	// Includes are the files the map included, in the order they were read.
+	Includes []string
+}
+
+// CoordinatesGeographic marks maps whose stations are placed by latitude
+// and longitude.
+const CoordinatesGeographic = "geographic"
+
+// Location is where a map file defines a station or connection. Line and
+// Col are 1-based.
+type Location struct {
+	File string
+	Line int
+	Col  int
+}
+
+// Locations are where the map defines its stations, by name, and its
+// connections, by start and end station. Networks not read from a map
+// have none.
+type Locations struct {
+	Stations    map[string]Location
+	Connections map[[2]string]Location
+}
+
+// Network is a validated map: its stations, its connections and the adjacency
+// between stations. Both the Dijkstra and the A* pathfinding work on a Network.
+type Network struct {
+	Stations    map[string]Station
+	Connections Connections
+	Adjacency   map[string][]string
+	Metadata    Metadata
+	Locations   Locations
+}
+
+// NewNetwork returns an empty network.
+func NewNetwork() *Network {
+	return &Network{
+		Stations:  make(map[string]Station),
+		Adjacency: make(map[string][]string),
+		Locations: Locations{
+			Stations:    make(map[string]Location),
+			Connections: make(map[[2]string]Location),
+		},
+	}
+}
+
+// AddStation adds a station to the network.
+func (n *Network) AddStation(station Station) {
+	n.Stations[station.Name] = station
+}
+
+// AddConnection adds a connection and makes its end a neighbour of its start,
+// and for undirected connections also the start a neighbour of the end.
+func (n *Network) AddConnection(connection Connection) {
+	from := connection.Start.Name
+	to := connection.End.Name
+	n.Connections = append(n.Connections, connection)
+	n.Adjacency[from] = append(n.Adjacency[from], to)
+	if !connection.Directed {
+		n.Adjacency[to] = append(n.Adjacency[to], from)
+	}
+}
+
+// HasStation reports whether the network contains a station with the given name.
+func (n *Network) HasStation(name string) bool {
+	_, exists := n.Stations[name]
+	return exists
+}
+
+// StationNames returns the names of the stations of the network in order.
+func (n *Network) StationNames() []string {
+	names := make([]string, 0, len(n.Stations))
+	for name := range n.Stations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Geographic reports whether the stations of the network are placed by
+// latitude and longitude.
+func (n *Network) Geographic() bool {
+	return n.Metadata.Coordinates == CoordinatesGeographic
+}
+
+// Projection returns a function that places stations on a flat map, with x
+// to the right and y downwards: at X and Y on a grid map, and in kilometres
+// east and south of the north-west corner on a geographic map.
+func (n *Network) Projection() func(Station) (float64, float64) {
+	if !n.Geographic() {
+		return func(station Station) (float64, float64) {
+			return float64(station.X), float64(station.Y)
+		}
+	}
+
+	stations := make([]Station, 0, len(n.Stations))
+	for _, station := range n.Stations {
+		stations = append(stations, station)
+	}
+	metres := GeographicProjection(stations)
+	return func(station Station) (float64, float64) {
+		x, y := metres(station)
+		return x / 1000, y / 1000
+	}
+}
+
+// GeographicProjection returns a function that places stations by their
+// latitude and longitude on a flat map, in metres east and south of the
+// north-west corner of stations. It is an equirectangular projection
+// around the middle of stations.
+func GeographicProjection(stations []Station) func(Station) (float64, float64) {
+	var minLat, maxLat, minLon float64
+	for i, station := range stations {
+		if i == 0 || station.Lat < minLat {
+			minLat = station.Lat
+		}
+		if i == 0 || station.Lat > maxLat {
+			maxLat = station.Lat
+		}
+		if i == 0 || station.Lon < minLon {
+			minLon = station.Lon
+		}
+	}
+	metresPerDegree := EarthRadius * math.Pi / 180
+	lonScale := math.Cos((minLat + maxLat) / 2 * math.Pi / 180)
+	return func(station Station) (float64, float64) {
+		return (station.Lon - minLon) * lonScale * metresPerDegree, (maxLat - station.Lat) * metresPerDegree
+	}
+}
+
+// StationCapacity returns how many trains the station can hold at once.
+// Stations without a capacity in the map hold one train.
+func (n *Network) StationCapacity(name string) int {
+	if capacity := n.Stations[name].Capacity; capacity > 0 {
+		return capacity
+	}
+	return 1
+}
+
 // Train represents a train with an ID and color.
 type Train struct {
 	ID    int
 	Color string
 }
+
+// NewTrain returns the train with the given ID, coloured red, yellow, blue
+// and green in turn. Color is the ANSI code used in the printed movements.
+func NewTrain(id int) Train {
+	colors := []string{"31", "33", "34", "32"} // Red, Yellow, Blue, Green
+	return Train{ID: id, Color: colors[(id-1)%len(colors)]}
+}
+
+// ColorName returns the name of the train's colour, for example "red".
+func (t Train) ColorName() string {
+	switch t.Color {
+	case "31":
+		return "red"
+	case "33":
+		return "yellow"
+	case "34":
+		return "blue"
+	case "32":
+		return "green"
+	}
+	return "black"
+}
+
+// Move is a train moving to a neighbouring station in one turn.
+type Move struct {
+	Train Train
+	From  string
+	To    string
+}
+
+// Schedule is the result of a simulation: every train and the moves made in
+// each turn, until all trains reached End.
+type Schedule struct {
+	Start  string
+	End    string
+	Trains []Train
+	Turns  [][]Move
+}
+
+// Lines returns one line per turn with the moves of the turn, coloured for
+// the terminal, for example "T1-victoria T2-euston".
+func (s Schedule) Lines() []string {
+	lines := make([]string, len(s.Turns))
+	for i, turn := range s.Turns {
+		moves := make([]string, len(turn))
+		for j, move := range turn {
+			moves[j] = fmt.Sprintf("\033[%smT%d-%s\033[0m", move.Train.Color, move.Train.ID, move.To)
+		}
+		lines[i] = strings.Join(moves, " ")
+	}
+	return lines
+}
+
+// Paths returns the stations each train went through, by train ID.
+func (s Schedule) Paths() map[int][]string {
+	paths := make(map[int][]string)
+	for _, train := range s.Trains {
+		paths[train.ID] = []string{s.Start}
+	}
+	for _, turn := range s.Turns {
+		for _, move := range turn {
+			paths[move.Train.ID] = append(paths[move.Train.ID], move.To)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,50 @@
+package network
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	grid := Station{X: 0, Y: 0}.Distance(Station{X: 3, Y: 4})
+	if grid != 5 {
+		t.Errorf("grid distance: got %v, want 5", grid)
+	}
+	// Helsinki to Tampere is about 160 km as the crow flies.
+	helsinki := Station{Lat: 60.1719, Lon: 24.9414, Geographic: true}
+	tampere := Station{Lat: 61.4981, Lon: 23.7610, Geographic: true}
+	if got := helsinki.Distance(tampere); math.Abs(got-160000) > 2000 {
+		t.Errorf("geographic distance: got %.0f m, want about 160000 m", got)
+	}
+}
+
+func TestGeographicProjection(t *testing.T) {
+	north := Station{Lat: 61, Lon: 25, Geographic: true}
+	south := Station{Lat: 60, Lon: 24, Geographic: true}
+	metres := GeographicProjection([]Station{north, south})
+
+	metresPerDegree := EarthRadius * math.Pi / 180
+	lonScale := math.Cos(60.5 * math.Pi / 180)
+	tests := []struct {
+		station Station
+		x, y    float64
+	}{
+		{north, metresPerDegree * lonScale, 0},
+		{south, 0, metresPerDegree},
+	}
+	for _, test := range tests {
+		x, y := metres(test.station)
+		if math.Abs(x-test.x) > 1e-6 || math.Abs(y-test.y) > 1e-6 {
+			t.Errorf("station at %v, %v: got %.1f, %.1f, want %.1f, %.1f", test.station.Lat, test.station.Lon, x, y, test.x, test.y)
+		}
+	}
+
+	net := NewNetwork()
+	net.Metadata.Coordinates = CoordinatesGeographic
+	north.Name, south.Name = "north", "south"
+	net.AddStation(north)
+	net.AddStation(south)
+	if x, y := net.Projection()(south); x != 0 || math.Abs(y-metresPerDegree/1000) > 1e-9 {
+		t.Errorf("projection of a geographic map: got %.3f, %.3f km, want 0, %.3f", x, y, metresPerDegree/1000)
+	}
+}
@@ -0,0 +1,285 @@
+package parser
+
+import (
+	"regexp"
+	network "stations/go/network/dijkstra"
+)
+
+// stationNameRegex is the rule every station name has to follow.
+var stationNameRegex = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// StationEntry is a station as read from a map, with where its name and
+// coordinates were found. Positions left empty fall back to Pos.
+type StationEntry struct {
+	network.Station
+	Pos, XPos, YPos Position
+}
+
+// TrackEntry is a connection as read from a map, before its stations are
+// looked up. Positions left empty fall back to Pos.
+type TrackEntry struct {
+	From, To string
+	// Time and Capacity are 0 when the map does not give them.
+	Time     int
+	Directed bool
+	Capacity int
+
+	Pos, FromPos, ToPos Position
+}
+
+// Builder assembles a network one station and connection at a time. Every
+// map format goes through a Builder, so a map is checked the same way
+// whatever format it was written in.
+type Builder struct {
+	net         *network.Network
+	diagnostics Diagnostics
+	// Names declared by stations, even broken ones, so that their
+	// connections do not report the station as missing a second time.
+	declared            map[string]struct{}
+	existingConnections map[string]struct{}
+	stationCount        int
+	connectionCount     int
+	// file is the map file being read, given to positions without one.
+	file string
+	// stationPos is where each station was added.
+	stationPos map[string]Position
+	// metadataPos is where the map sets each header field, by the name of
+	// its Header constant, when the format tells.
+	metadataPos map[string]Position
+	options     ParseOptions
+}
+
+// NewBuilder returns a builder for an empty network that checks it by the
+// default rules.
+func NewBuilder() *Builder {
+	return NewBuilderWithOptions(ParseOptions{})
+}
+
+// NewBuilderWithOptions returns a builder for an empty network that checks
+// it by the rules of options.
+func NewBuilderWithOptions(options ParseOptions) *Builder {
+	return &Builder{
+		options:             options,
+		net:                 network.NewNetwork(),
+		declared:            make(map[string]struct{}),
+		existingConnections: make(map[string]struct{}),
+		stationPos:          make(map[string]Position),
+	}
+}
+
+// setFile sets the map file that positions without a file are in, for
+// maps made of several files.
+func (b *Builder) setFile(file string) {
+	b.file = file
+}
+
+// inFile returns pos in the file being read, unless it names its own file
+// or concerns no line at all.
+func (b *Builder) inFile(pos Position) Position {
+	if pos.File == "" && pos.Line > 0 {
+		pos.File = b.file
+	}
+	return pos
+}
+
+// Errorf records a problem that the reader of a map format found itself.
+func (b *Builder) Errorf(pos Position, code, format string, args ...interface{}) {
+	b.diagnostics.errorAt(b.inFile(pos), code, format, args...)
+}
+
+// Declare records a station name whose station could not be added, so that
+// connections to it are not reported as well.
+func (b *Builder) Declare(name string) {
+	b.declared[name] = struct{}{}
+}
+
+// HasStation reports whether a station with the given name has been added.
+func (b *Builder) HasStation(name string) bool {
+	return b.net.HasStation(name)
+}
+
+// SetMetadata sets the metadata of the network. Build checks that its
+// default stations exist.
+func (b *Builder) SetMetadata(metadata network.Metadata) {
+	b.net.Metadata = metadata
+}
+
+// setMetadataPos sets where the map sets its header fields, by the name of
+// their Header constant, for the diagnostics about their values.
+func (b *Builder) setMetadataPos(positions map[string]Position) {
+	b.metadataPos = positions
+}
+
+// AddStation checks the station and adds it to the network.
+func (b *Builder) AddStation(entry StationEntry) bool {
+	name := entry.Name
+	if !b.options.stationName().MatchString(name) {
+		b.Errorf(entry.Pos, CodeInvalidStationName, "invalid station name: %s", name)
+		return false
+	}
+	_, alreadyDeclared := b.declared[name]
+	b.Declare(name)
+
+	if entry.Geographic && !(entry.Lat >= -90 && entry.Lat <= 90) {
+		b.Errorf(orPos(entry.XPos, entry.Pos), CodeInvalidX, "invalid latitude for station %s", name)
+		return false
+	}
+	if entry.Geographic && !(entry.Lon >= -180 && entry.Lon <= 180) {
+		b.Errorf(orPos(entry.YPos, entry.Pos), CodeInvalidY, "invalid longitude for station %s", name)
+		return false
+	}
+	if entry.X < 0 && !b.options.NegativeCoordinates {
+		b.Errorf(orPos(entry.XPos, entry.Pos), CodeInvalidX, "invalid x coordinate for station %s", name)
+		return false
+	}
+	if entry.Y < 0 && !b.options.NegativeCoordinates {
+		b.Errorf(orPos(entry.YPos, entry.Pos), CodeInvalidY, "invalid y coordinate for station %s", name)
+		return false
+	}
+	if entry.Capacity < 0 {
+		b.Errorf(entry.Pos, CodeInvalidCapacity, "invalid capacity for station %s", name)
+		return false
+	}
+
+	pos := b.inFile(entry.Pos)
+	if alreadyDeclared && b.options.Duplicates == DuplicatesIgnored {
+		return false
+	}
+	if alreadyDeclared {
+		// Files of one map may share a station, as long as they
+		// define it the same way.
+		if first, exists := b.stationPos[name]; exists && first.File != pos.File {
+			if b.net.Stations[name] == entry.Station {
+				return true
+			}
+			b.Errorf(pos, CodeConflictingStation, "conflicting definition of station %s, first defined at %s", name, first)
+			return false
+		}
+		b.Errorf(entry.Pos, CodeDuplicateStation, "duplicate station name: %s", name)
+		return false
+	}
+	for _, station := range b.net.Stations {
+		samePlace := station.X == entry.X && station.Y == entry.Y
+		if entry.Geographic {
+			samePlace = station.Lat == entry.Lat && station.Lon == entry.Lon
+		}
+		if samePlace && b.options.Duplicates == DuplicatesFatal {
+			b.Errorf(orPos(entry.XPos, entry.Pos), CodeDuplicateCoordinates, "duplicate coordinates for station %s", name)
+			break
+		}
+	}
+
+	b.net.AddStation(entry.Station)
+	b.stationPos[name] = pos
+	b.net.Locations.Stations[name] = location(pos)
+	b.stationCount++
+	if limit := b.options.maxStations(); limit > 0 && b.stationCount == limit+1 {
+		b.Errorf(entry.Pos, CodeTooManyStations, "map contains more than %d stations", limit)
+	}
+	return true
+}
+
+// AddConnection looks up the stations of the track, checks it and adds it to
+// the network.
+func (b *Builder) AddConnection(entry TrackEntry) bool {
+	from := entry.From
+	to := entry.To
+
+	startStation, fromExists := b.net.Stations[from]
+	if _, ok := b.declared[from]; !fromExists && !ok {
+		b.Errorf(orPos(entry.FromPos, entry.Pos), CodeUnknownFromStation, "connection from non-existent station: %s", from)
+	}
+	endStation, toExists := b.net.Stations[to]
+	if _, ok := b.declared[to]; !toExists && !ok {
+		b.Errorf(orPos(entry.ToPos, entry.Pos), CodeUnknownToStation, "connection to non-existent station: %s", to)
+	}
+	if !fromExists || !toExists {
+		return false
+	}
+	if from == to {
+		b.Errorf(orPos(entry.ToPos, entry.Pos), CodeSameStartEnd, "connection with same start and end station: %s", from)
+		return false
+	}
+	if entry.Time < 0 {
+		b.Errorf(entry.Pos, CodeInvalidTravelTime, "invalid travel time between %s and %s: %d", from, to, entry.Time)
+		return false
+	}
+	if entry.Capacity < 0 {
+		b.Errorf(entry.Pos, CodeInvalidTrackCapacity, "invalid track capacity: %d", entry.Capacity)
+		return false
+	}
+
+	// A track occupies the direction it can be travelled in, an
+	// undirected track occupies both.
+	connectionKey := from + "->" + to
+	reverseConnectionKey := to + "->" + from
+	_, exists := b.existingConnections[connectionKey]
+	_, reverseExists := b.existingConnections[reverseConnectionKey]
+	if exists || (reverseExists && !entry.Directed) {
+		if b.options.Duplicates == DuplicatesIgnored {
+			return false
+		}
+		b.Errorf(entry.Pos, CodeDuplicateConnection, "duplicate connection between %s and %s", from, to)
+		return false
+	}
+
+	b.existingConnections[connectionKey] = struct{}{}
+	if !entry.Directed {
+		b.existingConnections[reverseConnectionKey] = struct{}{}
+	}
+
+	b.net.AddConnection(network.Connection{
+		Start:    startStation,
+		End:      endStation,
+		Time:     entry.Time,
+		Directed: entry.Directed,
+		Capacity: entry.Capacity,
+	})
+	b.net.Locations.Connections[[2]string{from, to}] = location(b.inFile(entry.Pos))
+	b.connectionCount++
+	if limit := b.options.maxConnections(); limit > 0 && b.connectionCount == limit+1 {
+		b.Errorf(entry.Pos, CodeTooManyConnections, "map contains more than %d connections", limit)
+	}
+	return true
+}
+
+// Build runs the checks that need the whole map and returns the network,
+// or every problem found as Diagnostics. Stations without connections do not
+// make a map invalid, lint.Lint reports them.
+func (b *Builder) Build() (*network.Network, error) {
+	defaults := []struct{ key, which, station string }{
+		{HeaderDefaultStart, "start", b.net.Metadata.DefaultStart},
+		{HeaderDefaultEnd, "end", b.net.Metadata.DefaultEnd},
+	}
+	for _, field := range defaults {
+		if field.station != "" && !b.HasStation(field.station) {
+			b.Errorf(b.metadataPos[field.key], CodeUnknownDefaultStation, "default %s station does not exist: %s", field.which, field.station)
+		}
+	}
+
+	if len(b.declared) == 0 {
+		b.Errorf(Position{}, CodeNoStations, "map does not contain any stations")
+	}
+
+	if len(b.net.Connections) == 0 && !b.diagnostics.HasErrors() {
+		b.Errorf(Position{}, CodeNoConnections, "map does not contain any connections")
+	}
+
+	if b.diagnostics.HasErrors() {
+		return nil, b.diagnostics
+	}
+	return b.net, nil
+}
+
+// orPos returns pos, or fallback when pos is empty.
+func orPos(pos, fallback Position) Position {
+	if pos == (Position{}) {
+		return fallback
+	}
+	return pos
+}
+
+// location turns a position into the location of a network.
+func location(pos Position) network.Location {
+	return network.Location{File: pos.File, Line: pos.Line, Col: pos.Col}
+}
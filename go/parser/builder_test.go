@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"io"
+	network "stations/go/network/dijkstra"
+	"strings"
+	"testing"
+)
+
+// Every format names default stations its own way, and the Builder checks
+// them the same way for all of them.
+func TestUnknownDefaultStations(t *testing.T) {
+	const graphmlDefaults = `
+  <key id="start" for="graph" attr.name="default-start" attr.type="string"/>
+  <key id="end" for="graph" attr.name="default-end" attr.type="string"/>`
+	tests := []struct {
+		name  string
+		parse func(io.Reader) (*network.Network, error)
+		text  string
+		want  []Diagnostic
+	}{
+		{"text", ParseNetwork, "# @default-start nope\n# @default-end b\n" + threeStations + "\nconnections:\na-b\n", []Diagnostic{
+			{Pos: Position{Line: 1, Col: 3}, Code: CodeUnknownDefaultStation, Message: "default start station does not exist: nope"},
+		}},
+		{"json", ParseJSON, `{"defaultStart": "a", "defaultEnd": "nope",
+			"stations": [{"name": "a", "x": 0, "y": 0}, {"name": "b", "x": 3, "y": 4}],
+			"connections": [{"from": "a", "to": "b"}]}`, []Diagnostic{
+			{Code: CodeUnknownDefaultStation, Message: "default end station does not exist: nope"},
+		}},
+		{"graphml", ParseGraphML, strings.Replace(graphml(`<data key="start">x</data><data key="end">y</data>`, graphmlNodes, `<edge source="a" target="b"/>`),
+			"<graph ", graphmlDefaults+"\n  <graph ", 1), []Diagnostic{
+			{Code: CodeUnknownDefaultStation, Message: "default start station does not exist: x"},
+			{Code: CodeUnknownDefaultStation, Message: "default end station does not exist: y"},
+		}},
+	}
+	for _, test := range tests {
+		_, err := test.parse(strings.NewReader(test.text))
+		diagnostics, ok := err.(Diagnostics)
+		if !ok || len(diagnostics) != len(test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.want)
+			continue
+		}
+		for i, diagnostic := range diagnostics {
+			if diagnostic != test.want[i] {
+				t.Errorf("%s: got %+v, want %+v", test.name, diagnostic, test.want[i])
+			}
+		}
+	}
+}
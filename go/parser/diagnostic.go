@@ -0,0 +1,187 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity tells how serious a diagnostic is.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	}
+	return "unknown"
+}
+
+// Stable codes for every problem the parser can report.
+const (
+	CodeNoStationsSection      = "E001"
+	CodeNoConnectionsSection   = "E002"
+	CodeNoStations             = "E003"
+	CodeNoConnections          = "E004"
+	CodeTooManyStations        = "E005"
+	CodeTooManyConnections     = "E006"
+	CodeInvalidStationLine     = "E007"
+	CodeInvalidStationName     = "E008"
+	CodeInvalidX               = "E009"
+	CodeInvalidY               = "E010"
+	CodeDuplicateStation       = "E011"
+	CodeDuplicateCoordinates   = "E012"
+	CodeInvalidConnection      = "E013"
+	CodeDuplicateConnection    = "E014"
+	CodeUnknownFromStation     = "E015"
+	CodeUnknownToStation       = "E016"
+	CodeSameStartEnd           = "E017"
+	CodeInvalidTravelTime      = "E018"
+	CodeInvalidCapacity        = "E019"
+	CodeInvalidTrackCapacity   = "E020"
+	CodeInvalidHeader          = "E021"
+	CodeDuplicateHeader        = "E022"
+	CodeUnknownDefaultStation  = "E023"
+	CodeInvalidJSON            = "E024"
+	CodeInvalidGraphML         = "E025"
+	CodeInvalidInclude         = "E026"
+	CodeConflictingStation     = "E027"
+	CodeDuplicateTrackCapacity = "E028"
+)
+
+// Stable codes for problems only a GTFS feed can have. The feed's stations
+// and connections are checked by the codes above.
+const (
+	CodeDuplicateStop       = "G001"
+	CodeUnknownStop         = "G002"
+	CodeUnknownTrip         = "G003"
+	CodeInvalidStopSequence = "G004"
+	CodeMissingStopLocation = "G005"
+)
+
+// Position is a location in a map file. Line and Col are 1-based,
+// a zero Line means the diagnostic concerns the whole file.
+type Position struct {
+	File string
+	Line int
+	Col  int
+}
+
+func (p Position) String() string {
+	var parts []string
+	if p.File != "" {
+		parts = append(parts, p.File)
+	}
+	if p.Line > 0 {
+		parts = append(parts, fmt.Sprint(p.Line))
+		if p.Col > 0 {
+			parts = append(parts, fmt.Sprint(p.Col))
+		}
+	}
+	return strings.Join(parts, ":")
+}
+
+// Diagnostic is a single problem found in a map.
+type Diagnostic struct {
+	Pos      Position
+	Severity Severity
+	Code     string
+	Message  string
+}
+
+// String formats the diagnostic like compiler output, e.g.
+// "maps/x.txt:14:3: E014 duplicate connection between a and b". Diagnostics
+// that are not errors name their severity, e.g.
+// "maps/x.txt: L003 info: dead-end spur from a: b, c".
+func (d Diagnostic) String() string {
+	message := d.Code + " " + d.Message
+	if d.Severity != SeverityError {
+		message = d.Code + " " + d.Severity.String() + ": " + d.Message
+	}
+	pos := d.Pos.String()
+	if pos == "" {
+		return message
+	}
+	return pos + ": " + message
+}
+
+// Diagnostics is every problem found in a map, in the order they were found.
+type Diagnostics []Diagnostic
+
+// Error joins all diagnostics, one per line.
+func (d Diagnostics) Error() string {
+	lines := make([]string, len(d))
+	for i, diagnostic := range d {
+		lines[i] = diagnostic.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// HasErrors reports whether any diagnostic has error severity.
+func (d Diagnostics) HasErrors() bool {
+	for _, diagnostic := range d {
+		if diagnostic.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// WithFile returns a copy of the diagnostics with the file name set on
+// those that do not name a file yet.
+func (d Diagnostics) WithFile(file string) Diagnostics {
+	out := make(Diagnostics, len(d))
+	for i, diagnostic := range d {
+		if diagnostic.Pos.File == "" {
+			diagnostic.Pos.File = file
+		}
+		out[i] = diagnostic
+	}
+	return out
+}
+
+// errorf records an error diagnostic at line, col.
+func (d *Diagnostics) errorf(line, col int, code, format string, args ...interface{}) {
+	d.errorAt(Position{Line: line, Col: col}, code, format, args...)
+}
+
+// errorAt records an error diagnostic at pos.
+func (d *Diagnostics) errorAt(pos Position, code, format string, args ...interface{}) {
+	*d = append(*d, Diagnostic{
+		Pos:      pos,
+		Severity: SeverityError,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// field is a trimmed piece of a line together with the 1-based column it starts at.
+type field struct {
+	text string
+	col  int
+}
+
+// splitFields splits line on sep, trimming each piece and remembering its
+// column. col is the column of the first byte of line.
+func splitFields(line string, col int, sep string) []field {
+	var fields []field
+	offset := col - 1
+	for _, part := range strings.Split(line, sep) {
+		trimmed := strings.TrimLeft(part, " \t")
+		fields = append(fields, field{
+			text: strings.TrimSpace(part),
+			col:  offset + len(part) - len(trimmed) + 1,
+		})
+		offset += len(part) + len(sep)
+	}
+	return fields
+}
@@ -0,0 +1,369 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	network "stations/go/network/dijkstra"
+	"strconv"
+	"strings"
+)
+
+/*
+GraphML maps are graphs as written by yEd, Gephi and other graph tools:
+
+	<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+	  <key id="x" for="node" attr.name="x" attr.type="int"/>
+	  <key id="y" for="node" attr.name="y" attr.type="int"/>
+	  <key id="time" for="edge" attr.name="time" attr.type="int"/>
+	  <graph edgedefault="undirected">
+	    <node id="waterloo"><data key="x">3</data><data key="y">1</data></node>
+	    <node id="victoria"><data key="x">6</data><data key="y">7</data></node>
+	    <edge source="waterloo" target="victoria"><data key="time">3</data></edge>
+	  </graph>
+	</graphml>
+
+A node's id is the station name. Data is matched by the attr.name of its key:
+"x", "y" and "capacity" on nodes ("lat" and "lon" instead of "x" and "y"
+when the graph's "coordinates" is "geographic"), "time" (or "weight") and "capacity" on
+edges, and the header fields "name", "author", "version", "default-start",
+"default-end" and "coordinates" on the graph. Grid coordinates, capacities
+and times are whole numbers, which may be written as doubles such as "3.0".
+Edges follow the graph's edgedefault unless they set "directed".
+*/
+
+// graphmlKey declares the name of the data with the given id.
+type graphmlKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+// graphmlData is a value of a node, edge or graph.
+type graphmlData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphmlNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphmlData `xml:"data"`
+}
+
+type graphmlEdge struct {
+	Source   string        `xml:"source,attr"`
+	Target   string        `xml:"target,attr"`
+	Directed string        `xml:"directed,attr,omitempty"`
+	Data     []graphmlData `xml:"data"`
+}
+
+// graphmlElement is a node or edge with where it starts.
+type graphmlElement struct {
+	node *graphmlNode
+	edge *graphmlEdge
+	pos  Position
+}
+
+// ParseGraphML parses a GraphML map and checks it the same way ParseNetwork
+// checks a text map.
+func ParseGraphML(r io.Reader) (*network.Network, error) {
+	return parseGraphML(r, ParseOptions{})
+}
+
+// parseGraphML parses the map checking it by the rules of options.
+func parseGraphML(r io.Reader, options ParseOptions) (*network.Network, error) {
+	builder := NewBuilderWithOptions(options)
+	decoder := xml.NewDecoder(r)
+
+	keys := make(map[string]string)
+	graphData := make(map[string]string)
+	var elements []graphmlElement
+	graphExists := false
+	directedDefault := false
+
+	position := func() Position {
+		line, col := decoder.InputPos()
+		return Position{Line: line, Col: col}
+	}
+
+	for {
+		pos := position()
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			builder.Errorf(position(), CodeInvalidGraphML, "invalid GraphML map: %s", err)
+			return nil, builder.diagnostics
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "key":
+			var key graphmlKey
+			err = decoder.DecodeElement(&key, &start)
+			keys[key.ID] = key.Name
+		case "graph":
+			if graphExists {
+				builder.Errorf(pos, CodeInvalidGraphML, "GraphML map contains more than one graph")
+				return nil, builder.diagnostics
+			}
+			graphExists = true
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "edgedefault" {
+					directedDefault = attr.Value == "directed"
+				}
+			}
+		case "data":
+			// Data directly in the graph describes the whole map.
+			var data graphmlData
+			err = decoder.DecodeElement(&data, &start)
+			graphData[data.Key] = strings.TrimSpace(data.Value)
+		case "node":
+			node := &graphmlNode{}
+			err = decoder.DecodeElement(node, &start)
+			elements = append(elements, graphmlElement{node: node, pos: pos})
+		case "edge":
+			edge := &graphmlEdge{}
+			err = decoder.DecodeElement(edge, &start)
+			elements = append(elements, graphmlElement{edge: edge, pos: pos})
+		}
+		if err != nil {
+			builder.Errorf(pos, CodeInvalidGraphML, "invalid GraphML map: %s", err)
+			return nil, builder.diagnostics
+		}
+	}
+
+	if !graphExists {
+		builder.Errorf(Position{}, CodeInvalidGraphML, "GraphML map does not contain a graph")
+		return nil, builder.diagnostics
+	}
+
+	// values looks the data of an element up by the names of its keys.
+	values := func(data []graphmlData) map[string]string {
+		named := make(map[string]string)
+		for _, d := range data {
+			name := keys[d.Key]
+			if name == "" {
+				name = d.Key
+			}
+			named[name] = strings.TrimSpace(d.Value)
+		}
+		return named
+	}
+
+	metadata := network.Metadata{}
+	for key, value := range graphData {
+		switch keys[key] {
+		case HeaderName:
+			metadata.Name = value
+		case HeaderAuthor:
+			metadata.Author = value
+		case HeaderVersion:
+			metadata.Version = value
+		case HeaderDefaultStart:
+			metadata.DefaultStart = value
+		case HeaderDefaultEnd:
+			metadata.DefaultEnd = value
+		case HeaderCoordinates:
+			if value != "grid" {
+				metadata.Coordinates = value
+			}
+		}
+	}
+	geographic := metadata.Coordinates == network.CoordinatesGeographic
+	if metadata.Coordinates != "" && !geographic {
+		builder.Errorf(Position{}, CodeInvalidHeader, "graph data %s must be grid or %s: %s", HeaderCoordinates, network.CoordinatesGeographic, metadata.Coordinates)
+		return nil, builder.diagnostics
+	}
+
+	// Nodes come first, so that edges can name nodes later in the file.
+	for _, element := range elements {
+		if element.node == nil {
+			continue
+		}
+		node := values(element.node.Data)
+		name := element.node.ID
+		station := network.Station{Name: name, Geographic: geographic}
+		if geographic {
+			var latErr, lonErr error
+			station.Lat, latErr = strconv.ParseFloat(node["lat"], 64)
+			station.Lon, lonErr = strconv.ParseFloat(node["lon"], 64)
+			if latErr != nil {
+				builder.Errorf(element.pos, CodeInvalidX, "invalid latitude for station %s", name)
+				builder.Declare(name)
+				continue
+			}
+			if lonErr != nil {
+				builder.Errorf(element.pos, CodeInvalidY, "invalid longitude for station %s", name)
+				builder.Declare(name)
+				continue
+			}
+		} else {
+			var xOK, yOK bool
+			station.X, xOK = graphmlInt(node["x"])
+			station.Y, yOK = graphmlInt(node["y"])
+			if !xOK {
+				builder.Errorf(element.pos, CodeInvalidX, "invalid x coordinate for station %s", name)
+				builder.Declare(name)
+				continue
+			}
+			if !yOK {
+				builder.Errorf(element.pos, CodeInvalidY, "invalid y coordinate for station %s", name)
+				builder.Declare(name)
+				continue
+			}
+		}
+		if text, exists := node["capacity"]; exists {
+			var ok bool
+			station.Capacity, ok = graphmlInt(text)
+			if !ok || station.Capacity <= 0 {
+				builder.Errorf(element.pos, CodeInvalidCapacity, "invalid capacity for station %s", name)
+				builder.Declare(name)
+				continue
+			}
+		}
+		builder.AddStation(StationEntry{Station: station, Pos: element.pos})
+	}
+
+	for _, element := range elements {
+		if element.edge == nil {
+			continue
+		}
+		edge := values(element.edge.Data)
+		from, to := element.edge.Source, element.edge.Target
+
+		time := 0
+		text, exists := edge["time"]
+		if !exists {
+			text, exists = edge["weight"]
+		}
+		if exists {
+			var ok bool
+			time, ok = graphmlInt(text)
+			if !ok || time <= 0 {
+				builder.Errorf(element.pos, CodeInvalidTravelTime, "invalid travel time between %s and %s: %s", from, to, text)
+				continue
+			}
+		}
+		capacity := 0
+		if text, exists := edge["capacity"]; exists {
+			var ok bool
+			capacity, ok = graphmlInt(text)
+			if !ok || capacity <= 0 {
+				builder.Errorf(element.pos, CodeInvalidTrackCapacity, "invalid track capacity: %s", text)
+				continue
+			}
+		}
+		directed := directedDefault
+		if element.edge.Directed != "" {
+			directed = element.edge.Directed == "true"
+		}
+
+		builder.AddConnection(TrackEntry{
+			From:     from,
+			To:       to,
+			Time:     time,
+			Directed: directed,
+			Capacity: capacity,
+			Pos:      element.pos,
+		})
+	}
+
+	builder.SetMetadata(metadata)
+	return builder.Build()
+}
+
+// graphmlInt reads a whole number. Graph tools often write numbers as
+// doubles, so "3.0" is read as 3, but "3.5" is not a whole number.
+func graphmlInt(text string) (int, bool) {
+	value, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || value != math.Trunc(value) || math.Abs(value) > math.MaxInt32 {
+		return 0, false
+	}
+	return int(value), true
+}
+
+// WriteGraphML writes the network as a GraphML map, with stations and
+// connections in the same canonical order as WriteMap.
+func WriteGraphML(w io.Writer, net *network.Network) error {
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintln(out, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(out, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`)
+	header := []struct{ key, value string }{
+		{HeaderName, net.Metadata.Name},
+		{HeaderAuthor, net.Metadata.Author},
+		{HeaderVersion, net.Metadata.Version},
+		{HeaderDefaultStart, net.Metadata.DefaultStart},
+		{HeaderDefaultEnd, net.Metadata.DefaultEnd},
+		{HeaderCoordinates, net.Metadata.Coordinates},
+	}
+	for _, field := range header {
+		if field.value != "" {
+			fmt.Fprintf(out, "  <key id=\"%s\" for=\"graph\" attr.name=\"%s\" attr.type=\"string\"/>\n", field.key, field.key)
+		}
+	}
+	if net.Geographic() {
+		fmt.Fprintln(out, `  <key id="lat" for="node" attr.name="lat" attr.type="double"/>`)
+		fmt.Fprintln(out, `  <key id="lon" for="node" attr.name="lon" attr.type="double"/>`)
+	} else {
+		fmt.Fprintln(out, `  <key id="x" for="node" attr.name="x" attr.type="int"/>`)
+		fmt.Fprintln(out, `  <key id="y" for="node" attr.name="y" attr.type="int"/>`)
+	}
+	fmt.Fprintln(out, `  <key id="capacity" for="node" attr.name="capacity" attr.type="int"/>`)
+	fmt.Fprintln(out, `  <key id="time" for="edge" attr.name="time" attr.type="int"/>`)
+	fmt.Fprintln(out, `  <key id="tracks" for="edge" attr.name="capacity" attr.type="int"/>`)
+	fmt.Fprintln(out, `  <graph id="network" edgedefault="undirected">`)
+	for _, field := range header {
+		if field.value != "" {
+			fmt.Fprintf(out, "    <data key=\"%s\">%s</data>\n", field.key, xmlText(field.value))
+		}
+	}
+
+	for _, name := range net.StationNames() {
+		station := net.Stations[name]
+		if station.Geographic {
+			fmt.Fprintf(out, "    <node id=\"%s\"><data key=\"lat\">%s</data><data key=\"lon\">%s</data>", xmlText(station.Name), formatDegrees(station.Lat), formatDegrees(station.Lon))
+		} else {
+			fmt.Fprintf(out, "    <node id=\"%s\"><data key=\"x\">%d</data><data key=\"y\">%d</data>", xmlText(station.Name), station.X, station.Y)
+		}
+		if station.Capacity > 0 {
+			fmt.Fprintf(out, "<data key=\"capacity\">%d</data>", station.Capacity)
+		}
+		fmt.Fprintln(out, "</node>")
+	}
+
+	for _, connection := range canonicalConnections(net) {
+		fmt.Fprintf(out, "    <edge source=\"%s\" target=\"%s\"", xmlText(connection.Start.Name), xmlText(connection.End.Name))
+		if connection.Directed {
+			fmt.Fprint(out, ` directed="true"`)
+		}
+		fmt.Fprint(out, ">")
+		if connection.Time > 0 {
+			fmt.Fprintf(out, "<data key=\"time\">%d</data>", connection.Time)
+		}
+		if connection.Capacity > 0 {
+			fmt.Fprintf(out, "<data key=\"tracks\">%d</data>", connection.Capacity)
+		}
+		fmt.Fprintln(out, "</edge>")
+	}
+
+	fmt.Fprintln(out, "  </graph>")
+	fmt.Fprintln(out, "</graphml>")
+	return out.Flush()
+}
+
+// xmlText escapes text for use in XML.
+func xmlText(text string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
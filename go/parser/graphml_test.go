@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+// graphml returns a GraphML map with the given graph data, nodes and edges.
+func graphml(graphData, nodes, edges string) string {
+	return `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="x" for="node" attr.name="x" attr.type="double"/>
+  <key id="y" for="node" attr.name="y" attr.type="double"/>
+  <key id="lat" for="node" attr.name="lat" attr.type="double"/>
+  <key id="lon" for="node" attr.name="lon" attr.type="double"/>
+  <key id="capacity" for="node" attr.name="capacity" attr.type="int"/>
+  <key id="weight" for="edge" attr.name="weight" attr.type="double"/>
+  <key id="coordinates" for="graph" attr.name="coordinates" attr.type="string"/>
+  <graph edgedefault="undirected">` + graphData + nodes + edges + `
+  </graph>
+</graphml>`
+}
+
+const (
+	graphmlNodeB = `
+    <node id="b"><data key="x">3</data><data key="y">4</data></node>`
+	graphmlNodes = `
+    <node id="a"><data key="x">0</data><data key="y">0</data></node>` + graphmlNodeB
+)
+
+func TestGraphMLWholeNumbers(t *testing.T) {
+	net, err := ParseGraphML(strings.NewReader(graphml("",
+		`<node id="a"><data key="x">1.0</data><data key="y">2</data></node>
+		<node id="b"><data key="x">3</data><data key="y">4.0</data><data key="capacity">2.0</data></node>`,
+		`<edge source="a" target="b"><data key="weight">5.0</data></edge>`)))
+	if err != nil {
+		t.Fatalf("parsing map: %v", err)
+	}
+	if a := net.Stations["a"]; a.X != 1 || a.Y != 2 {
+		t.Errorf("got a at %d,%d, want 1,2", a.X, a.Y)
+	}
+	if b := net.Stations["b"]; b.Capacity != 2 {
+		t.Errorf("got capacity %d for b, want 2", b.Capacity)
+	}
+	if time := net.Connections[0].Time; time != 5 {
+		t.Errorf("got travel time %d, want 5", time)
+	}
+}
+
+func TestGraphMLInvalidValues(t *testing.T) {
+	const geographic = `<data key="coordinates">geographic</data>`
+	const geoNodes = `
+    <node id="a"><data key="lat">60.1</data><data key="lon">24.9</data></node>
+    <node id="b"><data key="lat">61.5</data><data key="lon">23.8</data></node>`
+	const edge = `<edge source="a" target="b"/>`
+	tests := []struct {
+		name  string
+		graph string
+		want  string
+	}{
+		{"fractional x", graphml("", `<node id="a"><data key="x">1.5</data><data key="y">0</data></node>`+graphmlNodeB, edge), CodeInvalidX},
+		{"fractional y", graphml("", `<node id="a"><data key="x">1</data><data key="y">0.2</data></node>`+graphmlNodeB, edge), CodeInvalidY},
+		{"fractional weight", graphml("", graphmlNodes, `<edge source="a" target="b"><data key="weight">2.5</data></edge>`), CodeInvalidTravelTime},
+		{"zero capacity", graphml("", graphmlNodes+`<node id="c"><data key="x">9</data><data key="y">9</data><data key="capacity">0</data></node>`, edge), CodeInvalidCapacity},
+		{"zero geographic capacity", graphml(geographic, geoNodes+`<node id="c"><data key="lat">1</data><data key="lon">1</data><data key="capacity">0</data></node>`, edge), CodeInvalidCapacity},
+		{"missing latitude", graphml(geographic, geoNodes+`<node id="c"><data key="lon">1</data></node>`, edge), CodeInvalidX},
+	}
+	for _, test := range tests {
+		_, err := ParseGraphML(strings.NewReader(test.graph))
+		diagnostics, ok := err.(Diagnostics)
+		if !ok {
+			t.Errorf("%s: got %v, want diagnostics", test.name, err)
+			continue
+		}
+		if len(diagnostics) != 1 || diagnostics[0].Code != test.want {
+			t.Errorf("%s: got %v, want [%s]", test.name, diagnostics, test.want)
+		}
+	}
+}
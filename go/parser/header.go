@@ -0,0 +1,82 @@
+package parser
+
+import (
+	network "stations/go/network/dijkstra"
+	"strings"
+)
+
+// Header fields that a map can set in "# @field value" comments before its
+// first section.
+const (
+	HeaderName         = "name"
+	HeaderAuthor       = "author"
+	HeaderVersion      = "version"
+	HeaderDefaultStart = "default-start"
+	HeaderDefaultEnd   = "default-end"
+	HeaderCoordinates  = "coordinates"
+)
+
+// header collects the metadata of a map from its header comments.
+type header struct {
+	metadata network.Metadata
+	// positions remembers where each field was set, for duplicates and for
+	// default stations that turn out not to exist, see Builder.Build.
+	positions map[string]Position
+}
+
+func newHeader() *header {
+	return &header{positions: make(map[string]Position)}
+}
+
+// parseComment reads a comment found before the first section. col is the
+// column of the first byte after the "#". Comments that are not "@field
+// value" pairs, or name a field this parser does not know, are plain comments.
+// Like on any other line, a further "#" starts a comment, which is not part
+// of the value.
+func (h *header) parseComment(comment string, lineNumber, col int, builder *Builder) {
+	comment, _, _ = strings.Cut(comment, "#")
+	text := strings.TrimSpace(comment)
+	if !strings.HasPrefix(text, "@") {
+		return
+	}
+	col += strings.Index(comment, text)
+
+	key, value, _ := strings.Cut(text[1:], " ")
+	value = strings.TrimSpace(value)
+
+	var target *string
+	switch key {
+	case HeaderName:
+		target = &h.metadata.Name
+	case HeaderAuthor:
+		target = &h.metadata.Author
+	case HeaderVersion:
+		target = &h.metadata.Version
+	case HeaderDefaultStart:
+		target = &h.metadata.DefaultStart
+	case HeaderDefaultEnd:
+		target = &h.metadata.DefaultEnd
+	case HeaderCoordinates:
+		target = &h.metadata.Coordinates
+	default:
+		return
+	}
+
+	if value == "" {
+		builder.Errorf(Position{Line: lineNumber, Col: col}, CodeInvalidHeader, "missing value for header field @%s", key)
+		return
+	}
+	if _, exists := h.positions[key]; exists {
+		builder.Errorf(Position{Line: lineNumber, Col: col}, CodeDuplicateHeader, "duplicate header field @%s", key)
+		return
+	}
+	if key == HeaderCoordinates && value != network.CoordinatesGeographic && value != "grid" {
+		builder.Errorf(Position{Line: lineNumber, Col: col}, CodeInvalidHeader, "header field @%s must be grid or %s: %s", key, network.CoordinatesGeographic, value)
+		return
+	}
+	h.positions[key] = Position{Line: lineNumber, Col: col}
+	if key == HeaderCoordinates && value == "grid" {
+		value = ""
+	}
+	*target = value
+}
@@ -0,0 +1,60 @@
+package parser
+
+import (
+	"reflect"
+	network "stations/go/network/dijkstra"
+	"testing"
+)
+
+const headerConnections = "\nconnections:\na-b # the only track\nb-c\n"
+
+func TestHeader(t *testing.T) {
+	net := parseMap(t, `# A map of three stations
+# @name London # since 1863
+#   @author   Laura
+# @version 2
+# @default-start a
+# @default-end c
+# @colour red
+#@nonsense
+# name: not a field
+`+threeStations+headerConnections)
+	want := network.Metadata{
+		Name:         "London",
+		Author:       "Laura",
+		Version:      "2",
+		DefaultStart: "a",
+		DefaultEnd:   "c",
+	}
+	if !reflect.DeepEqual(net.Metadata, want) {
+		t.Errorf("got metadata %+v, want %+v", net.Metadata, want)
+	}
+}
+
+// Only comments before the first section make up the header.
+func TestHeaderAfterFirstSection(t *testing.T) {
+	net := parseMap(t, "stations:\n# @name London\na,0,0 # @author Laura\nb,3,4\nc,6,8\n"+headerConnections)
+	if net.Metadata.Name != "" || net.Metadata.Author != "" {
+		t.Errorf("got name %q and author %q, want none", net.Metadata.Name, net.Metadata.Author)
+	}
+}
+
+func TestInvalidHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []string
+	}{
+		{"missing value", "# @name\n", []string{CodeInvalidHeader}},
+		{"only a comment as value", "# @name # old\n", []string{CodeInvalidHeader}},
+		{"duplicate field", "# @name London\n# @name Paris\n", []string{CodeDuplicateHeader}},
+		{"invalid coordinates", "# @coordinates polar\n", []string{CodeInvalidHeader}},
+		{"unknown default station", "# @default-end nope\n", []string{CodeUnknownDefaultStation}},
+	}
+	for _, test := range tests {
+		got := codes(t, test.header+threeStations+headerConnections)
+		if len(got) != len(test.want) || got[0] != test.want[0] {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
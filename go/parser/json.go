@@ -0,0 +1,419 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	network "stations/go/network/dijkstra"
+	"strings"
+)
+
+/*
+JSON maps describe the same networks as text maps:
+
+	{
+	  "name": "London",
+	  "author": "Laura",
+	  "version": "2",
+	  "defaultStart": "waterloo",
+	  "defaultEnd": "st_pancras",
+	  "stations": [
+	    {"name": "waterloo", "x": 3, "y": 1},
+	    {"name": "victoria", "x": 6, "y": 7, "capacity": 2}
+	  ],
+	  "connections": [
+	    {"from": "waterloo", "to": "victoria", "time": 3, "directed": true, "capacity": 2}
+	  ]
+	}
+
+Only "stations" and "connections" are required. With "coordinates":
+"geographic" stations give "lat" and "lon" in degrees instead of "x" and
+"y". In a station, "capacity" is
+the number of trains it holds. In a connection, "time" is the travel time,
+"directed" makes it a one-way track from "from" to "to", and "capacity" is
+the number of parallel tracks.
+*/
+
+// jsonStation is a station in a JSON map.
+type jsonStation struct {
+	Name     string `json:"name"`
+	X        int    `json:"x"`
+	Y        int    `json:"y"`
+	Capacity int    `json:"capacity,omitempty"`
+}
+
+// jsonGeoStation is a station in a JSON map with geographic coordinates.
+type jsonGeoStation struct {
+	Name     string  `json:"name"`
+	Lat      float64 `json:"lat"`
+	Lon      float64 `json:"lon"`
+	Capacity int     `json:"capacity,omitempty"`
+}
+
+// jsonStationFields is a station as read from a JSON map, before the map
+// says how its stations are placed. Coordinates it does not give are nil.
+type jsonStationFields struct {
+	Name     string   `json:"name"`
+	X        *int     `json:"x"`
+	Y        *int     `json:"y"`
+	Lat      *float64 `json:"lat"`
+	Lon      *float64 `json:"lon"`
+	Capacity int      `json:"capacity"`
+}
+
+// jsonConnection is a connection in a JSON map.
+type jsonConnection struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Time     int    `json:"time,omitempty"`
+	Directed bool   `json:"directed,omitempty"`
+	Capacity int    `json:"capacity,omitempty"`
+}
+
+// jsonHeader is what a JSON map says about itself.
+type jsonHeader struct {
+	Name         string `json:"name,omitempty"`
+	Author       string `json:"author,omitempty"`
+	Version      string `json:"version,omitempty"`
+	DefaultStart string `json:"defaultStart,omitempty"`
+	DefaultEnd   string `json:"defaultEnd,omitempty"`
+	Coordinates  string `json:"coordinates,omitempty"`
+}
+
+// jsonMap is the document written by WriteJSON for a grid map.
+type jsonMap struct {
+	jsonHeader
+	Stations    []jsonStation    `json:"stations"`
+	Connections []jsonConnection `json:"connections"`
+}
+
+// jsonGeoMap is the document written by WriteJSON for a geographic map.
+type jsonGeoMap struct {
+	jsonHeader
+	Stations    []jsonGeoStation `json:"stations"`
+	Connections []jsonConnection `json:"connections"`
+}
+
+// ParseJSON parses a JSON map and checks it the same way ParseNetwork checks
+// a text map.
+func ParseJSON(r io.Reader) (*network.Network, error) {
+	return parseJSON(r, ParseOptions{})
+}
+
+// parseJSON parses the map checking it by the rules of options.
+func parseJSON(r io.Reader, options ParseOptions) (*network.Network, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := NewBuilderWithOptions(options)
+	reader := &jsonReader{data: data, decoder: json.NewDecoder(bytes.NewReader(data))}
+
+	var metadata network.Metadata
+	var stations, connections []jsonElement
+	stationsExist, connectionsExist := false, false
+
+	err = reader.object(func(key string) error {
+		switch key {
+		case "name":
+			return reader.decoder.Decode(&metadata.Name)
+		case "author":
+			return reader.decoder.Decode(&metadata.Author)
+		case "version":
+			return reader.decoder.Decode(&metadata.Version)
+		case "defaultStart":
+			return reader.decoder.Decode(&metadata.DefaultStart)
+		case "defaultEnd":
+			return reader.decoder.Decode(&metadata.DefaultEnd)
+		case "coordinates":
+			if err := reader.decoder.Decode(&metadata.Coordinates); err != nil {
+				return err
+			}
+			if metadata.Coordinates != network.CoordinatesGeographic && metadata.Coordinates != "grid" {
+				return errors.New("coordinates must be grid or " + network.CoordinatesGeographic)
+			}
+			if metadata.Coordinates == "grid" {
+				metadata.Coordinates = ""
+			}
+			return nil
+		case "stations":
+			var err error
+			stationsExist = true
+			stations, err = reader.array(func() interface{} { return &jsonStationFields{} })
+			return err
+		case "connections":
+			var err error
+			connectionsExist = true
+			connections, err = reader.array(func() interface{} { return &jsonConnection{} })
+			return err
+		}
+		return errors.New("unknown field " + key)
+	})
+	if err != nil {
+		builder.Errorf(reader.errorPosition(), CodeInvalidJSON, "invalid JSON map: %s", err)
+		return nil, builder.diagnostics
+	}
+
+	// A missing list makes every other problem a consequence of it,
+	// so only the missing lists are reported.
+	var sectionDiagnostics Diagnostics
+	if !stationsExist {
+		sectionDiagnostics.errorf(0, 0, CodeNoStationsSection, "map does not contain a \"stations\" list")
+	}
+	if !connectionsExist {
+		sectionDiagnostics.errorf(0, 0, CodeNoConnectionsSection, "map does not contain a \"connections\" list")
+	}
+	if sectionDiagnostics.HasErrors() {
+		return nil, sectionDiagnostics
+	}
+
+	geographic := metadata.Coordinates == network.CoordinatesGeographic
+	for _, element := range stations {
+		station := element.value.(*jsonStationFields)
+		if station.Capacity == 0 && element.has("capacity") {
+			builder.Errorf(element.pos, CodeInvalidCapacity, "invalid capacity for station %s", station.Name)
+			continue
+		}
+		if !checkJSONCoordinates(builder, element.pos, station, geographic) {
+			builder.Declare(station.Name)
+			continue
+		}
+		entry := StationEntry{
+			Station: network.Station{
+				Name:       station.Name,
+				Capacity:   station.Capacity,
+				Geographic: geographic,
+			},
+			Pos: element.pos,
+		}
+		if geographic {
+			entry.Lat, entry.Lon = *station.Lat, *station.Lon
+		} else {
+			entry.X, entry.Y = *station.X, *station.Y
+		}
+		builder.AddStation(entry)
+	}
+	for _, element := range connections {
+		connection := element.value.(*jsonConnection)
+		if connection.Time == 0 && element.has("time") {
+			builder.Errorf(element.pos, CodeInvalidTravelTime, "invalid travel time between %s and %s: 0", connection.From, connection.To)
+			continue
+		}
+		if connection.Capacity == 0 && element.has("capacity") {
+			builder.Errorf(element.pos, CodeInvalidTrackCapacity, "invalid track capacity: 0")
+			continue
+		}
+		builder.AddConnection(TrackEntry{
+			From:     connection.From,
+			To:       connection.To,
+			Time:     connection.Time,
+			Directed: connection.Directed,
+			Capacity: connection.Capacity,
+			Pos:      element.pos,
+		})
+	}
+
+	builder.SetMetadata(metadata)
+	return builder.Build()
+}
+
+// checkJSONCoordinates reports a station that lacks a coordinate of its map,
+// or gives one of the other kind of map, with the codes a text map uses for
+// its x and y.
+func checkJSONCoordinates(builder *Builder, pos Position, station *jsonStationFields, geographic bool) bool {
+	if geographic {
+		switch {
+		case station.X != nil:
+			builder.Errorf(pos, CodeInvalidX, "station %s gives x in a geographic map", station.Name)
+		case station.Y != nil:
+			builder.Errorf(pos, CodeInvalidY, "station %s gives y in a geographic map", station.Name)
+		case station.Lat == nil:
+			builder.Errorf(pos, CodeInvalidX, "missing latitude for station %s", station.Name)
+		case station.Lon == nil:
+			builder.Errorf(pos, CodeInvalidY, "missing longitude for station %s", station.Name)
+		default:
+			return true
+		}
+		return false
+	}
+	switch {
+	case station.Lat != nil:
+		builder.Errorf(pos, CodeInvalidX, "station %s gives lat in a grid map", station.Name)
+	case station.Lon != nil:
+		builder.Errorf(pos, CodeInvalidY, "station %s gives lon in a grid map", station.Name)
+	case station.X == nil:
+		builder.Errorf(pos, CodeInvalidX, "missing x coordinate for station %s", station.Name)
+	case station.Y == nil:
+		builder.Errorf(pos, CodeInvalidY, "missing y coordinate for station %s", station.Name)
+	default:
+		return true
+	}
+	return false
+}
+
+// jsonElement is an element of a JSON list, decoded, with where it starts.
+type jsonElement struct {
+	value interface{}
+	raw   json.RawMessage
+	pos   Position
+}
+
+// has reports whether the element spells out the given field, which tells
+// an explicit zero apart from a missing field.
+func (e jsonElement) has(field string) bool {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(e.raw, &fields) != nil {
+		return false
+	}
+	_, exists := fields[field]
+	return exists
+}
+
+// jsonReader walks a JSON document token by token so that every element
+// of a list can be given its line and column.
+type jsonReader struct {
+	data    []byte
+	decoder *json.Decoder
+	// element is where the list element being decoded starts, if any.
+	element *Position
+}
+
+// object reads an object, calling field for each key with the decoder
+// positioned at its value.
+func (j *jsonReader) object(field func(key string) error) error {
+	if err := j.delim('{'); err != nil {
+		return err
+	}
+	for j.decoder.More() {
+		token, err := j.decoder.Token()
+		if err != nil {
+			return err
+		}
+		if err := field(token.(string)); err != nil {
+			return err
+		}
+	}
+	return j.delim('}')
+}
+
+// array reads a list, decoding each element into a value made by newValue.
+func (j *jsonReader) array(newValue func() interface{}) ([]jsonElement, error) {
+	if err := j.delim('['); err != nil {
+		return nil, err
+	}
+	var elements []jsonElement
+	for j.decoder.More() {
+		pos := j.position(j.nextValue())
+		var raw json.RawMessage
+		if err := j.decoder.Decode(&raw); err != nil {
+			return nil, err
+		}
+		value := newValue()
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(value); err != nil {
+			j.element = &pos
+			return nil, err
+		}
+		elements = append(elements, jsonElement{value: value, raw: raw, pos: pos})
+	}
+	return elements, j.delim(']')
+}
+
+// delim reads the next token and fails unless it is the given delimiter.
+func (j *jsonReader) delim(want json.Delim) error {
+	token, err := j.decoder.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != want {
+		return errors.New("expected " + want.String())
+	}
+	return nil
+}
+
+// nextValue returns the offset of the value the decoder reads next,
+// skipping the white space and comma in front of it.
+func (j *jsonReader) nextValue() int64 {
+	offset := j.decoder.InputOffset()
+	for offset < int64(len(j.data)) && strings.ContainsRune(" \t\r\n,", rune(j.data[offset])) {
+		offset++
+	}
+	return offset
+}
+
+// errorPosition returns where reading the document failed: the list element
+// that could not be decoded, or else the decoder's offset.
+func (j *jsonReader) errorPosition() Position {
+	if j.element != nil {
+		return *j.element
+	}
+	return j.position(j.decoder.InputOffset())
+}
+
+// position turns a byte offset into a line and column.
+func (j *jsonReader) position(offset int64) Position {
+	if offset > int64(len(j.data)) {
+		offset = int64(len(j.data))
+	}
+	before := j.data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := int(offset) - bytes.LastIndexByte(before, '\n')
+	return Position{Line: line, Col: col}
+}
+
+// WriteJSON writes the network as a JSON map, with stations and connections
+// in the same canonical order as WriteMap.
+func WriteJSON(w io.Writer, net *network.Network) error {
+	header := jsonHeader{
+		Name:         net.Metadata.Name,
+		Author:       net.Metadata.Author,
+		Version:      net.Metadata.Version,
+		DefaultStart: net.Metadata.DefaultStart,
+		DefaultEnd:   net.Metadata.DefaultEnd,
+		Coordinates:  net.Metadata.Coordinates,
+	}
+	connections := []jsonConnection{}
+	for _, connection := range canonicalConnections(net) {
+		connections = append(connections, jsonConnection{
+			From:     connection.Start.Name,
+			To:       connection.End.Name,
+			Time:     connection.Time,
+			Directed: connection.Directed,
+			Capacity: connection.Capacity,
+		})
+	}
+
+	var document interface{}
+	if net.Geographic() {
+		geoMap := jsonGeoMap{jsonHeader: header, Stations: []jsonGeoStation{}, Connections: connections}
+		for _, name := range net.StationNames() {
+			station := net.Stations[name]
+			geoMap.Stations = append(geoMap.Stations, jsonGeoStation{
+				Name:     station.Name,
+				Lat:      station.Lat,
+				Lon:      station.Lon,
+				Capacity: station.Capacity,
+			})
+		}
+		document = geoMap
+	} else {
+		gridMap := jsonMap{jsonHeader: header, Stations: []jsonStation{}, Connections: connections}
+		for _, name := range net.StationNames() {
+			station := net.Stations[name]
+			gridMap.Stations = append(gridMap.Stations, jsonStation{
+				Name:     station.Name,
+				X:        station.X,
+				Y:        station.Y,
+				Capacity: station.Capacity,
+			})
+		}
+		document = gridMap
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
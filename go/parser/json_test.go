@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// jsonCodes returns the codes of the diagnostics parsing a JSON map reports.
+func jsonCodes(t *testing.T, text string) []string {
+	t.Helper()
+	_, err := ParseJSON(strings.NewReader(text))
+	diagnostics, ok := err.(Diagnostics)
+	if !ok {
+		t.Fatalf("parsing map: got %v, want diagnostics", err)
+	}
+	var found []string
+	for _, diagnostic := range diagnostics {
+		found = append(found, diagnostic.Code)
+	}
+	return found
+}
+
+func TestJSONCoordinates(t *testing.T) {
+	const connections = `"connections": [{"from": "a", "to": "b"}]`
+	tests := []struct {
+		name     string
+		coords   string
+		stations string
+		want     string
+	}{
+		{"missing x", "", `{"name": "a", "y": 0}, {"name": "b", "x": 1, "y": 1}`, CodeInvalidX},
+		{"missing y", "", `{"name": "a", "x": 0}, {"name": "b", "x": 1, "y": 1}`, CodeInvalidY},
+		{"lat in a grid map", "", `{"name": "a", "x": 0, "y": 0, "lat": 1}, {"name": "b", "x": 1, "y": 1}`, CodeInvalidX},
+		{"lon in a grid map", "", `{"name": "a", "x": 0, "y": 0, "lon": 1}, {"name": "b", "x": 1, "y": 1}`, CodeInvalidY},
+		{"missing lat", `"coordinates": "geographic",`, `{"name": "a", "lon": 0}, {"name": "b", "lat": 1, "lon": 1}`, CodeInvalidX},
+		{"missing lon", `"coordinates": "geographic",`, `{"name": "a", "lat": 0}, {"name": "b", "lat": 1, "lon": 1}`, CodeInvalidY},
+		{"x in a geographic map", `"coordinates": "geographic",`, `{"name": "a", "x": 0, "lat": 0, "lon": 0}, {"name": "b", "lat": 1, "lon": 1}`, CodeInvalidX},
+	}
+	for _, test := range tests {
+		got := jsonCodes(t, `{`+test.coords+`"stations": [`+test.stations+`], `+connections+`}`)
+		if len(got) != 1 || got[0] != test.want {
+			t.Errorf("%s: got %v, want [%s]", test.name, got, test.want)
+		}
+	}
+}
+
+func TestJSONCoordinatesAfterStations(t *testing.T) {
+	net, err := ParseJSON(strings.NewReader(`{
+  "stations": [{"name": "a", "lat": 60.1, "lon": 24.9}, {"name": "b", "lat": 61.5, "lon": 23.8}],
+  "connections": [{"from": "a", "to": "b"}],
+  "coordinates": "geographic"
+}`))
+	if err != nil {
+		t.Fatalf("parsing map: %v", err)
+	}
+	if a := net.Stations["a"]; !a.Geographic || a.Lat != 60.1 || a.Lon != 24.9 {
+		t.Errorf("got station %+v, want a at 60.1, 24.9", a)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	for _, text := range []string{
+		threeStations + "\nconnections:\na-b,7 x2\nb->c\n",
+		"# @coordinates geographic\nstations:\na,60.1,24.9\nb,61.5,23.8,2\n\nconnections:\na-b\n",
+	} {
+		net := parseMap(t, text)
+		var written bytes.Buffer
+		if err := WriteJSON(&written, net); err != nil {
+			t.Fatalf("writing JSON: %v", err)
+		}
+		read, err := ParseJSON(&written)
+		if err != nil {
+			t.Fatalf("reading written JSON: %v\n%s", err, written.String())
+		}
+		var want, got bytes.Buffer
+		if err := WriteMap(&want, net); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteMap(&got, read); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("round trip changed the map:\n%s\nwant:\n%s", got.String(), want.String())
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"regexp"
+)
+
+// Default limits on the size of a map.
+const (
+	DefaultMaxStations    = 10000
+	DefaultMaxConnections = 10000
+	// NoLimit as a limit lets a map be of any size, like any other
+	// limit below 0.
+	NoLimit = -1
+)
+
+// DuplicatePolicy says what happens to a station or connection that repeats
+// one already in the map.
+type DuplicatePolicy int
+
+const (
+	// DuplicatesFatal reports duplicate station names, coordinates and
+	// connections as errors.
+	DuplicatesFatal DuplicatePolicy = iota
+	// DuplicatesIgnored keeps the first of duplicate stations and
+	// connections and drops the others, and lets stations share coordinates.
+	DuplicatesIgnored
+)
+
+// ParseOptions changes the rules a map is checked by. The zero value checks
+// maps by the default rules.
+type ParseOptions struct {
+	// MaxStations and MaxConnections are the most stations and connections
+	// a map may have, DefaultMaxStations and DefaultMaxConnections when 0
+	// and no limit when below 0, see NoLimit.
+	MaxStations    int
+	MaxConnections int
+	// StationName is the rule station names have to follow, lower case
+	// letters, digits and underscores when nil.
+	StationName *regexp.Regexp
+	// Duplicates says what happens to duplicate stations and connections.
+	Duplicates DuplicatePolicy
+	// NegativeCoordinates lets stations have coordinates below 0.
+	NegativeCoordinates bool
+}
+
+// maxStations returns the station limit, or 0 for none.
+func (o ParseOptions) maxStations() int {
+	return limit(o.MaxStations, DefaultMaxStations)
+}
+
+// maxConnections returns the connection limit, or 0 for none.
+func (o ParseOptions) maxConnections() int {
+	return limit(o.MaxConnections, DefaultMaxConnections)
+}
+
+// limit returns the limit value stands for: fallback for 0, and 0, no
+// limit, for any value below 0.
+func limit(value, fallback int) int {
+	if value == 0 {
+		return fallback
+	}
+	if value < 0 {
+		return 0
+	}
+	return value
+}
+
+// stationName returns the rule station names have to follow.
+func (o ParseOptions) stationName() *regexp.Regexp {
+	if o.StationName != nil {
+		return o.StationName
+	}
+	return stationNameRegex
+}
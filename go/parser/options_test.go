@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// The zero value checks maps by the default rules the audit relies on.
+func TestParseOptionsZeroValue(t *testing.T) {
+	var options ParseOptions
+	if got := options.maxStations(); got != DefaultMaxStations {
+		t.Errorf("got station limit %d, want %d", got, DefaultMaxStations)
+	}
+	if got := options.maxConnections(); got != DefaultMaxConnections {
+		t.Errorf("got connection limit %d, want %d", got, DefaultMaxConnections)
+	}
+	if options.stationName() != stationNameRegex {
+		t.Errorf("got station name rule %s, want %s", options.stationName(), stationNameRegex)
+	}
+	if options.Duplicates != DuplicatesFatal || options.NegativeCoordinates {
+		t.Errorf("got duplicates %d and negative coordinates %v, want fatal duplicates and none", options.Duplicates, options.NegativeCoordinates)
+	}
+}
+
+// A limit of 0 is the default, and any limit below 0 is none.
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		limit, want int
+	}{
+		{0, DefaultMaxStations},
+		{5, 5},
+		{NoLimit, 0},
+		{-5, 0},
+	}
+	for _, test := range tests {
+		options := ParseOptions{MaxStations: test.limit, MaxConnections: test.limit}
+		if got := options.maxStations(); got != test.want {
+			t.Errorf("MaxStations %d: got limit %d, want %d", test.limit, got, test.want)
+		}
+		if got := options.maxConnections(); got != test.want {
+			t.Errorf("MaxConnections %d: got limit %d, want %d", test.limit, got, test.want)
+		}
+	}
+}
+
+// manyStations returns a map of n stations in a row.
+func manyStations(n int) string {
+	var text strings.Builder
+	text.WriteString("stations:\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&text, "s%d,%d,0\n", i, i)
+	}
+	text.WriteString("\nconnections:\n")
+	for i := 1; i < n; i++ {
+		fmt.Fprintf(&text, "s%d-s%d\n", i-1, i)
+	}
+	return text.String()
+}
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ParseOptions
+		text    string
+		// want is the codes of the diagnostics, nil for a valid map.
+		want []string
+	}{
+		{"default station limit", ParseOptions{}, manyStations(DefaultMaxStations + 1), []string{CodeTooManyStations}},
+		{"station limit", ParseOptions{MaxStations: 2}, manyStations(3), []string{CodeTooManyStations}},
+		{"connection limit", ParseOptions{MaxConnections: 1}, manyStations(3), []string{CodeTooManyConnections}},
+		{"limits kept", ParseOptions{MaxStations: 3, MaxConnections: 2}, manyStations(3), nil},
+
+		{"default station names", ParseOptions{}, "stations:\nA,0,0\nb,1,1\n\nconnections:\nA-b\n", []string{CodeInvalidStationName, CodeUnknownFromStation}},
+		{"station name rule", ParseOptions{StationName: regexp.MustCompile(`^[A-Za-z]+$`)}, "stations:\nA,0,0\nb,1,1\n\nconnections:\nA-b\n", nil},
+		{"station name rule rejects", ParseOptions{StationName: regexp.MustCompile(`^[A-Z]+$`)}, "stations:\nA,0,0\nb,1,1\n\nconnections:\nA-b\n", []string{CodeInvalidStationName, CodeUnknownToStation}},
+
+		{"duplicates fatal", ParseOptions{}, "stations:\na,0,0\na,1,1\nb,0,0\n\nconnections:\na-b\nb-a\n", []string{CodeDuplicateStation, CodeDuplicateCoordinates, CodeDuplicateConnection}},
+		{"duplicates ignored", ParseOptions{Duplicates: DuplicatesIgnored}, "stations:\na,0,0\na,1,1\nb,0,0\n\nconnections:\na-b\nb-a\n", nil},
+
+		{"negative coordinates rejected", ParseOptions{}, "stations:\na,-1,0\nb,0,-1\n\nconnections:\na-b\n", []string{CodeInvalidX, CodeInvalidY}},
+		{"negative coordinates", ParseOptions{NegativeCoordinates: true}, "stations:\na,-1,0\nb,0,-1\n\nconnections:\na-b\n", nil},
+	}
+	for _, test := range tests {
+		_, err := ParseNetworkWithOptions(strings.NewReader(test.text), test.options)
+		var got []string
+		if err != nil {
+			diagnostics, ok := err.(Diagnostics)
+			if !ok {
+				t.Fatalf("%s: got %v, want diagnostics", test.name, err)
+			}
+			for _, diagnostic := range diagnostics {
+				got = append(got, diagnostic.Code)
+			}
+		}
+		if strings.Join(got, " ") != strings.Join(test.want, " ") {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
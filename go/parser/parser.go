@@ -3,190 +3,427 @@ package parser
 import (
 	"bufio"
 	"errors"
-	"fmt"
 	"io"
 	"os"
-	"regexp"
+	"path/filepath"
+	"sort"
 	network "stations/go/network/dijkstra"
 	"strconv"
 	"strings"
 )
 
-// ParseConnections parses the connections from the reader
+// ParseConnections parses the map from the reader and returns only its connections.
 func ParseConnections(r io.Reader) (network.Connections, error) {
-	scanner := bufio.NewScanner(r)
-	connectionsForStations := make(map[string]bool)
-	stationsSectionExists := false
-	connectionsSectionExists := false
+	net, err := ParseNetwork(r)
+	if err != nil {
+		return nil, err
+	}
+	return net.Connections, nil
+}
 
-	// First pass to check for sections
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "stations:" {
-			stationsSectionExists = true
-		} else if line == "connections:" {
-			connectionsSectionExists = true
-		}
-		if stationsSectionExists && connectionsSectionExists {
-			break
+// ParseNetwork parses the map from the reader in a single pass, so any
+// io.Reader works, including pipes and stdin. Parsing does not stop at the
+// first problem: every problem found is returned as Diagnostics. Comments
+// start with # anywhere on a line, and the "# @field value" comments before
+// the first section fill in the network's Metadata. Files named in
+// "include:" lines are found relative to the working directory.
+func ParseNetwork(r io.Reader) (*network.Network, error) {
+	return ParseNetworkWithOptions(r, ParseOptions{})
+}
+
+// ParseNetworkWithOptions parses the map from the reader like ParseNetwork,
+// checking it by the rules of options.
+func ParseNetworkWithOptions(r io.Reader, options ParseOptions) (*network.Network, error) {
+	return parseText(r, "", "", options)
+}
+
+// parseText parses the text map named file from the reader, with included
+// files found relative to dir.
+func parseText(r io.Reader, file, dir string, options ParseOptions) (*network.Network, error) {
+	reader := &textReader{
+		builder:   NewBuilderWithOptions(options),
+		header:    newHeader(),
+		including: make(map[string]int),
+		included:  make(map[string]bool),
+		cycles:    make(map[string]bool),
+	}
+	reader.builder.setFile(file)
+	// A map read from a file may not include that file again.
+	if dir != "" {
+		if key, err := filepath.Abs(file); err == nil {
+			reader.including[key] = len(reader.chain)
+			reader.chain = append(reader.chain, file)
+			reader.included[key] = true
 		}
 	}
-
-	if err := scanner.Err(); err != nil {
+	if err := reader.read(r, dir, true); err != nil {
 		return nil, err
 	}
 
-	if !stationsSectionExists {
-		return nil, errors.New("map does not contain a \"stations:\" section")
+	// A missing section makes every other problem a consequence of it,
+	// so only the missing sections are reported, after the includes
+	// that failed and may be why they are missing.
+	var sectionDiagnostics Diagnostics
+	for _, diagnostic := range reader.builder.diagnostics {
+		if diagnostic.Code == CodeInvalidInclude {
+			sectionDiagnostics = append(sectionDiagnostics, diagnostic)
+		}
 	}
-
-	if !connectionsSectionExists {
-		return nil, errors.New("map does not contain a \"connections:\" section")
+	if !reader.stationsSectionExists {
+		sectionDiagnostics.errorf(0, 0, CodeNoStationsSection, "map does not contain a \"stations:\" section")
 	}
-
-	// Reset the scanner for the second pass
-	if seeker, ok := r.(io.Seeker); ok {
-		seeker.Seek(0, io.SeekStart)
-		scanner = bufio.NewScanner(r)
-	} else {
-		return nil, errors.New("reader does not support seeking")
+	if !reader.connectionsSectionExists {
+		sectionDiagnostics.errorf(0, 0, CodeNoConnectionsSection, "map does not contain a \"connections:\" section")
+	}
+	if !reader.stationsSectionExists || !reader.connectionsSectionExists {
+		return nil, sectionDiagnostics
 	}
 
-	connections := network.Connections{}
-	stations := make(map[string]network.Station)
-	existingConnections := make(map[string]struct{})
-	section := ""
+	reader.header.metadata.Includes = reader.includes
+	reader.builder.SetMetadata(reader.header.metadata)
+	reader.builder.setMetadataPos(reader.header.positions)
+	return reader.builder.Build()
+}
 
-	stationCount := 0
-	connectionCount := 0
+// textReader reads a text map and the files it includes into one builder.
+type textReader struct {
+	builder                  *Builder
+	header                   *header
+	stationsSectionExists    bool
+	connectionsSectionExists bool
+	// chain is the files being read, each included by the one before it,
+	// and including where each of them is in chain, to catch include
+	// cycles. included is every file read, so that a file is only read once.
+	chain     []string
+	including map[string]int
+	included  map[string]bool
+	// cycles are the include cycles reported, so that each is reported once.
+	cycles   map[string]bool
+	includes []string
+}
 
-	// Define regex for station name validation
-	nameRegex := regexp.MustCompile(`^[a-z0-9_]+$`)
+// read parses one map file. Only the top file has a header.
+func (t *textReader) read(r io.Reader, dir string, top bool) error {
+	scanner := bufio.NewScanner(r)
+	builder := t.builder
+	section := ""
+	lineNumber := 0
 
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+		lineNumber++
+		raw := scanner.Text()
+		// Everything after a # is a comment. Comments before the first
+		// section make up the header of the map.
+		if comment := strings.Index(raw, "#"); comment >= 0 {
+			if section == "" && top {
+				t.header.parseComment(raw[comment+1:], lineNumber, comment+2, builder)
+			}
+			raw = raw[:comment]
+		}
+		line := strings.TrimSpace(raw)
 		if line == "" {
 			continue
 		}
+		lineCol := strings.Index(raw, line) + 1
 
 		if line == "stations:" {
 			section = "stations"
+			t.stationsSectionExists = true
 			continue
 		} else if line == "connections:" {
 			section = "connections"
+			t.connectionsSectionExists = true
+			continue
+		} else if path, isInclude := strings.CutPrefix(line, "include:"); isInclude {
+			if err := t.include(strings.TrimSpace(path), dir, Position{Line: lineNumber, Col: lineCol}); err != nil {
+				return err
+			}
 			continue
 		}
 
 		if section == "stations" {
-			parts := strings.Split(line, ",")
-			if len(parts) != 3 {
-				return nil, fmt.Errorf("invalid station line: %s", line)
+			parseStationLine(raw, lineNumber, builder, t.header.metadata.Coordinates == network.CoordinatesGeographic)
+		} else if section == "connections" {
+			connection, ok := parseConnectionLine(raw, lineNumber, builder)
+			if !ok {
+				continue
 			}
+			builder.AddConnection(TrackEntry{
+				From:     connection.from.text,
+				To:       connection.to.text,
+				Time:     connection.travelTime,
+				Directed: connection.directed,
+				Capacity: connection.capacity,
+				Pos:      Position{Line: lineNumber, Col: lineCol},
+				FromPos:  Position{Line: lineNumber, Col: connection.from.col},
+				ToPos:    Position{Line: lineNumber, Col: connection.to.col},
+			})
+		}
+	}
 
-			name := strings.TrimSpace(parts[0])
-			if !nameRegex.MatchString(name) {
-				return nil, fmt.Errorf("invalid station name: %s", name)
-			}
+	return scanner.Err()
+}
 
-			x, err := strconv.Atoi(strings.TrimSpace(parts[1]))
-			if err != nil || x < 0 {
-				return nil, fmt.Errorf("invalid x coordinate for station %s", name)
-			}
-			y, err := strconv.Atoi(strings.TrimSpace(parts[2]))
-			if err != nil || y < 0 {
-				return nil, fmt.Errorf("invalid y coordinate for station %s", name)
-			}
+// include reads the text map at path, relative to dir, into the same
+// network. A file included a second time is skipped, a file that includes
+// itself, directly or through other files, is reported.
+func (t *textReader) include(path, dir string, pos Position) error {
+	builder := t.builder
+	if path == "" {
+		builder.Errorf(pos, CodeInvalidInclude, "include without a file")
+		return nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	key, err := filepath.Abs(path)
+	if err != nil {
+		key = path
+	}
+	if start, exists := t.including[key]; exists {
+		cycle := append(append([]string{}, t.chain[start:]...), path)
+		members := append([]string{}, cycle[:len(cycle)-1]...)
+		sort.Strings(members)
+		if id := strings.Join(members, "\n"); !t.cycles[id] {
+			t.cycles[id] = true
+			builder.Errorf(pos, CodeInvalidInclude, "include cycle: %s", strings.Join(cycle, " includes "))
+		}
+		return nil
+	}
+	if t.included[key] {
+		return nil
+	}
 
-			if _, exists := stations[name]; exists {
-				return nil, fmt.Errorf("duplicate station name: %s", name)
-			}
-			for _, station := range stations {
-				if station.X == x && station.Y == y {
-					return nil, fmt.Errorf("duplicate coordinates for station %s", name)
-				}
-			}
+	file, err := os.Open(path)
+	if err != nil {
+		builder.Errorf(pos, CodeInvalidInclude, "cannot include %s: %s", path, errors.Unwrap(err))
+		return nil
+	}
+	defer file.Close()
 
-			stations[name] = network.Station{Name: name, X: x, Y: y}
-			stationCount++
-		} else if section == "connections" {
-			parts := strings.Split(line, "-")
-			if len(parts) != 2 {
-				return nil, fmt.Errorf("invalid connection line: %s", line)
-			}
+	t.including[key] = len(t.chain)
+	t.chain = append(t.chain, path)
+	t.included[key] = true
+	t.includes = append(t.includes, path)
+	parent := builder.file
+	builder.setFile(path)
+	err = t.read(file, filepath.Dir(path), false)
+	builder.setFile(parent)
+	delete(t.including, key)
+	t.chain = t.chain[:len(t.chain)-1]
+	return err
+}
 
-			from := strings.TrimSpace(parts[0])
-			to := strings.TrimSpace(parts[1])
+// parseStationLine reads a station line of the form "name,x,y" with an
+// optional fourth field for the number of trains the station holds. On a
+// geographic map the line is "name,latitude,longitude" instead.
+func parseStationLine(raw string, lineNumber int, builder *Builder, geographic bool) {
+	line := strings.TrimSpace(raw)
+	lineCol := strings.Index(raw, line) + 1
 
-			startStation, exists := stations[from]
-			if !exists {
-				return nil, fmt.Errorf("connection from non-existent station: %s", from)
-			}
-			endStation, exists := stations[to]
-			if !exists {
-				return nil, fmt.Errorf("connection to non-existent station: %s", to)
-			}
-			if from == to {
-				return nil, fmt.Errorf("connection with same start and end station: %s", from)
-			}
+	parts := splitFields(raw, 1, ",")
+	if len(parts) != 3 && len(parts) != 4 {
+		builder.Errorf(Position{Line: lineNumber, Col: lineCol}, CodeInvalidStationLine, "invalid station line: %s", line)
+		return
+	}
 
-			connectionKey := from + "-" + to
-			reverseConnectionKey := to + "-" + from
-			if _, exists := existingConnections[connectionKey]; exists {
-				return nil, fmt.Errorf("duplicate connection between %s and %s", from, to)
-			}
-			if _, exists := existingConnections[reverseConnectionKey]; exists {
-				return nil, fmt.Errorf("duplicate connection between %s and %s", from, to)
-			}
+	name := parts[0].text
+	station := network.Station{Name: name, Geographic: geographic}
+	var err error
+	if geographic {
+		station.Lat, err = strconv.ParseFloat(parts[1].text, 64)
+		if err != nil {
+			builder.Errorf(Position{Line: lineNumber, Col: parts[1].col}, CodeInvalidX, "invalid latitude for station %s", name)
+			builder.Declare(name)
+			return
+		}
+		station.Lon, err = strconv.ParseFloat(parts[2].text, 64)
+		if err != nil {
+			builder.Errorf(Position{Line: lineNumber, Col: parts[2].col}, CodeInvalidY, "invalid longitude for station %s", name)
+			builder.Declare(name)
+			return
+		}
+	} else {
+		station.X, err = strconv.Atoi(parts[1].text)
+		if err != nil {
+			builder.Errorf(Position{Line: lineNumber, Col: parts[1].col}, CodeInvalidX, "invalid x coordinate for station %s", name)
+			builder.Declare(name)
+			return
+		}
+		station.Y, err = strconv.Atoi(parts[2].text)
+		if err != nil {
+			builder.Errorf(Position{Line: lineNumber, Col: parts[2].col}, CodeInvalidY, "invalid y coordinate for station %s", name)
+			builder.Declare(name)
+			return
+		}
+	}
+	if len(parts) == 4 {
+		station.Capacity, err = strconv.Atoi(parts[3].text)
+		if err != nil || station.Capacity <= 0 {
+			builder.Errorf(Position{Line: lineNumber, Col: parts[3].col}, CodeInvalidCapacity, "invalid capacity for station %s", name)
+			builder.Declare(name)
+			return
+		}
+	}
 
-			existingConnections[connectionKey] = struct{}{}
-			existingConnections[reverseConnectionKey] = struct{}{}
+	builder.AddStation(StationEntry{
+		Station: station,
+		Pos:     Position{Line: lineNumber, Col: parts[0].col},
+		XPos:    Position{Line: lineNumber, Col: parts[1].col},
+		YPos:    Position{Line: lineNumber, Col: parts[2].col},
+	})
+}
 
-			connections = append(connections, network.Connection{
-				Start: startStation,
-				End:   endStation,
-			})
-			connectionsForStations[from] = true // Mark that this station has a connection
-			connectionsForStations[to] = true 
-			connectionCount++
-		}
+// connectionLine is a connection line split into its parts.
+type connectionLine struct {
+	from, to field
+	// travelTime is 0 when the line does not give one.
+	travelTime int
+	// directed is set for one-way "from->to" tracks.
+	directed bool
+	// capacity is the number of parallel tracks, 0 when the line does not give one.
+	capacity int
+}
 
-		if stationCount > 10000 {
-			return nil, errors.New("map contains more than 10000 stations")
-		}
+// parseConnectionLine splits a connection line of the form "from-to" or
+// "from->to", optionally followed by ",time" and by " xN" for N parallel tracks.
+func parseConnectionLine(raw string, lineNumber int, builder *Builder) (connectionLine, bool) {
+	line := strings.TrimSpace(raw)
+	lineCol := strings.Index(raw, line) + 1
+	connection := connectionLine{}
 
-		if connectionCount > 10000 {
-			return nil, errors.New("map contains more than 10000 connections")
+	// Track counts are the words at the end of the line that start with
+	// an x, as long as what comes before them is still a whole connection
+	// and not "a - x2" with a station named x2.
+	route := strings.TrimRight(raw, " \t")
+	var counts []field
+	for {
+		i := strings.LastIndexAny(route, " \t")
+		if i < 0 {
+			break
 		}
+		prefix := strings.TrimSpace(route[:i])
+		token := route[i+1:]
+		if !strings.HasPrefix(token, "x") || prefix == "" || strings.ContainsAny(prefix[len(prefix)-1:], "->,") {
+			break
+		}
+		counts = append([]field{{text: token, col: i + 2}}, counts...)
+		route = strings.TrimRight(route[:i], " \t")
 	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	for i, count := range counts {
+		if i > 0 {
+			builder.Errorf(Position{Line: lineNumber, Col: count.col}, CodeDuplicateTrackCapacity, "track capacity given more than once: %s", count.text)
+			return connectionLine{}, false
+		}
+		capacity, err := strconv.Atoi(count.text[1:])
+		if err != nil || capacity <= 0 {
+			builder.Errorf(Position{Line: lineNumber, Col: count.col}, CodeInvalidTrackCapacity, "invalid track capacity: %s", count.text)
+			return connectionLine{}, false
+		}
+		connection.capacity = capacity
 	}
 
-	if len(stations) == 0 {
-		return nil, errors.New("map does not contain any stations")
+	pieces := splitFields(route, 1, ",")
+	if len(pieces) > 2 {
+		builder.Errorf(Position{Line: lineNumber, Col: lineCol}, CodeInvalidConnection, "invalid connection line: %s", line)
+		return connectionLine{}, false
 	}
 
-	if len(connections) == 0 {
-		return nil, errors.New("map does not contain any connections")
+	separator := "-"
+	if strings.Contains(pieces[0].text, "->") {
+		separator = "->"
+		connection.directed = true
 	}
+	parts := splitFields(pieces[0].text, pieces[0].col, separator)
+	// Station names have no spaces, so words left between the stations
+	// and the end of the line make it invalid, for example "a-b 2".
+	if len(parts) != 2 || strings.ContainsAny(parts[0].text, " \t") || strings.ContainsAny(parts[1].text, " \t") {
+		builder.Errorf(Position{Line: lineNumber, Col: lineCol}, CodeInvalidConnection, "invalid connection line: %s", line)
+		return connectionLine{}, false
+	}
+	connection.from = parts[0]
+	connection.to = parts[1]
 
-	// Check if every station has at least one connection
-	for station, hasConnection := range connectionsForStations {
-		if !hasConnection {
-			return nil, fmt.Errorf("no connection from station: %s", station)
+	if len(pieces) == 2 {
+		travelTime, err := strconv.Atoi(pieces[1].text)
+		if err != nil || travelTime <= 0 {
+			builder.Errorf(Position{Line: lineNumber, Col: pieces[1].col}, CodeInvalidTravelTime, "invalid travel time between %s and %s: %s", parts[0].text, parts[1].text, pieces[1].text)
+			return connectionLine{}, false
 		}
+		connection.travelTime = travelTime
 	}
 
-	return connections, nil
+	return connection, true
+}
+
+// Stdin is the file path that makes ReadMap read the map from standard input.
+const Stdin = "-"
+
+// ReadMap parses the map file at filePath, or standard input when filePath
+// is Stdin. Files ending in .json, and input that starts with a JSON object,
+// are read as JSON maps, files ending in .graphml, and input that starts
+// with an XML element, as GraphML maps. Text maps can include other text
+// maps, found relative to the including file. Diagnostics it returns carry the file path so they
+// can be printed as they are.
+func ReadMap(filePath string) (*network.Network, error) {
+	return ReadMapWithOptions(filePath, ParseOptions{})
 }
 
-func ReadMap(filePath string) (network.Connections, error) {
-	file, err := os.Open(filePath)
+// ReadMapWithOptions reads a map like ReadMap, checking it by the rules of options.
+func ReadMapWithOptions(filePath string, options ParseOptions) (*network.Network, error) {
+	var r io.Reader = os.Stdin
+	name := "<stdin>"
+	if filePath != Stdin {
+		file, err := os.Open(filePath)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+		name = filePath
+	}
+
+	input := bufio.NewReader(r)
+	dir := ""
+	if filePath != Stdin {
+		dir = filepath.Dir(filePath)
+	}
+	parse := func(r io.Reader, options ParseOptions) (*network.Network, error) {
+		return parseText(r, name, dir, options)
+	}
+	extension := filepath.Ext(filePath)
+	first := firstByte(input)
+	if strings.EqualFold(extension, ".json") || first == '{' {
+		parse = parseJSON
+	} else if strings.EqualFold(extension, ".graphml") || first == '<' {
+		parse = parseGraphML
+	}
+
+	net, err := parse(input, options)
+	var diagnostics Diagnostics
+	if errors.As(err, &diagnostics) {
+		return nil, diagnostics.WithFile(name)
+	}
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	net.Metadata.Source = filePath
+	return net, nil
+}
 
-	return ParseConnections(file)
+// firstByte returns the first byte of the input that is not white space, or
+// 0 for input without one: '{' opens a JSON map and '<' a GraphML map. It
+// only peeks, so the input can still be parsed.
+func firstByte(r *bufio.Reader) byte {
+	for n := 1; ; n++ {
+		peeked, err := r.Peek(n)
+		if err != nil {
+			return 0
+		}
+		switch peeked[n-1] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return peeked[n-1]
+	}
 }
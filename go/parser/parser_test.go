@@ -0,0 +1,229 @@
+package parser
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	network "stations/go/network/dijkstra"
+	"strings"
+	"testing"
+)
+
+// parseMap parses a text map that has to be valid.
+func parseMap(t *testing.T, text string) *network.Network {
+	t.Helper()
+	net, err := ParseNetwork(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("parsing map: %v", err)
+	}
+	return net
+}
+
+// codes returns the codes of the diagnostics parsing a text map reports.
+func codes(t *testing.T, text string) []string {
+	t.Helper()
+	_, err := ParseNetwork(strings.NewReader(text))
+	diagnostics, ok := err.(Diagnostics)
+	if !ok {
+		t.Fatalf("parsing map: got %v, want diagnostics", err)
+	}
+	var found []string
+	for _, diagnostic := range diagnostics {
+		found = append(found, diagnostic.Code)
+	}
+	return found
+}
+
+// connection returns the connection between from and to.
+func connection(t *testing.T, net *network.Network, from, to string) network.Connection {
+	t.Helper()
+	for _, connection := range net.Connections {
+		if connection.Start.Name == from && connection.End.Name == to {
+			return connection
+		}
+	}
+	t.Fatalf("no connection %s-%s", from, to)
+	return network.Connection{}
+}
+
+const threeStations = `stations:
+a,0,0
+b,3,4
+c,6,8
+`
+
+func TestTravelTimes(t *testing.T) {
+	net := parseMap(t, threeStations+`
+connections:
+a-b,7
+b-c
+`)
+	if got := connection(t, net, "a", "b"); got.Time != 7 || got.TravelTime() != 7 {
+		t.Errorf("a-b,7: got time %d and travel time %d, want 7", got.Time, got.TravelTime())
+	}
+	// Without a time the travel time is the distance between the stations.
+	if got := connection(t, net, "b", "c"); got.Time != 0 || got.TravelTime() != 5 {
+		t.Errorf("b-c: got time %d and travel time %d, want 0 and 5", got.Time, got.TravelTime())
+	}
+}
+
+func TestInvalidTravelTimes(t *testing.T) {
+	for _, line := range []string{"a-b,0", "a-b,-3", "a-b,x", "a-b,"} {
+		got := codes(t, threeStations+"connections:\n"+line+"\nb-c\n")
+		if len(got) != 1 || got[0] != CodeInvalidTravelTime {
+			t.Errorf("%s: got %v, want [%s]", line, got, CodeInvalidTravelTime)
+		}
+	}
+}
+
+func TestTrackCapacities(t *testing.T) {
+	net := parseMap(t, threeStations+`
+connections:
+a-b x2
+b->c,4 x3
+`)
+	if got := connection(t, net, "a", "b"); got.Capacity != 2 || got.Directed {
+		t.Errorf("a-b x2: got capacity %d, directed %v, want 2 and two-way", got.Capacity, got.Directed)
+	}
+	if got := connection(t, net, "b", "c"); got.Capacity != 3 || got.Time != 4 || !got.Directed {
+		t.Errorf("b->c,4 x3: got capacity %d, time %d, directed %v, want 3, 4 and one-way", got.Capacity, got.Time, got.Directed)
+	}
+}
+
+func TestInvalidTrackCapacities(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"a-b x0", CodeInvalidTrackCapacity},
+		{"a-b x", CodeInvalidTrackCapacity},
+		{"a-b xx", CodeInvalidTrackCapacity},
+		{"a-b x2 x3", CodeDuplicateTrackCapacity},
+		{"a-b,4 x2 x2", CodeDuplicateTrackCapacity},
+		{"a-b 2", CodeInvalidConnection},
+		{"a-b x2 y", CodeInvalidConnection},
+	}
+	for _, test := range tests {
+		got := codes(t, threeStations+"connections:\n"+test.line+"\nb-c\n")
+		if len(got) != 1 || got[0] != test.want {
+			t.Errorf("%s: got %v, want [%s]", test.line, got, test.want)
+		}
+	}
+}
+
+// A station may be named like a track count, as long as it is not written
+// after a space.
+func TestStationNamedLikeTrackCount(t *testing.T) {
+	net := parseMap(t, "stations:\na,0,0\nx2,1,1\n\nconnections:\na - x2\n")
+	if got := connection(t, net, "a", "x2"); got.Capacity != 0 {
+		t.Errorf("a - x2: got capacity %d, want 0", got.Capacity)
+	}
+}
+
+// writeMaps writes the given map files into a temporary directory and
+// returns it.
+func writeMaps(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestIncludes(t *testing.T) {
+	dir := writeMaps(t, map[string]string{
+		"top.txt":    "include: south.txt\ninclude: north.txt\n\nconnections:\nb-c\n",
+		"south.txt":  "include: shared.txt\n\nstations:\na,0,0\n\nconnections:\na-b\n",
+		"north.txt":  "include: shared.txt\n\nstations:\nc,6,8\n",
+		"shared.txt": "stations:\nb,3,4\n",
+	})
+	net, err := ReadMap(filepath.Join(dir, "top.txt"))
+	if err != nil {
+		t.Fatalf("reading map: %v", err)
+	}
+	if len(net.Stations) != 3 || len(net.Connections) != 2 {
+		t.Errorf("got %d stations and %d connections, want 3 and 2", len(net.Stations), len(net.Connections))
+	}
+	if got := len(net.Metadata.Includes); got != 3 {
+		t.Errorf("got includes %v, want shared.txt read once", net.Metadata.Includes)
+	}
+}
+
+func TestIncludeCycles(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		chain []string
+	}{
+		{"top includes itself", map[string]string{
+			"top.txt": threeStations + "include: top.txt\n\nconnections:\na-b\n",
+		}, []string{"top.txt", "top.txt"}},
+		{"through other files", map[string]string{
+			"top.txt": threeStations + "include: a.txt\n\nconnections:\na-b\n",
+			"a.txt":   "include: b.txt\n",
+			"b.txt":   "include: a.txt\ninclude: a.txt\n",
+		}, []string{"a.txt", "b.txt", "a.txt"}},
+	}
+	for _, test := range tests {
+		dir := writeMaps(t, test.files)
+		_, err := ReadMap(filepath.Join(dir, "top.txt"))
+		diagnostics, ok := err.(Diagnostics)
+		if !ok || len(diagnostics) != 1 || diagnostics[0].Code != CodeInvalidInclude {
+			t.Errorf("%s: got %v, want one %s", test.name, err, CodeInvalidInclude)
+			continue
+		}
+		var chain []string
+		for _, file := range test.chain {
+			chain = append(chain, filepath.Join(dir, file))
+		}
+		if want := "include cycle: " + strings.Join(chain, " includes "); diagnostics[0].Message != want {
+			t.Errorf("%s: got %q, want %q", test.name, diagnostics[0].Message, want)
+		}
+	}
+}
+
+func TestFirstByte(t *testing.T) {
+	tests := []struct {
+		input string
+		want  byte
+	}{
+		{"", 0},
+		{" \t\r\n", 0},
+		{`{"stations": []}`, '{'},
+		{"\n  <graphml>", '<'},
+		{"# @name x\nstations:\n", '#'},
+	}
+	for _, test := range tests {
+		input := bufio.NewReader(strings.NewReader(test.input))
+		if got := firstByte(input); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.input, got, test.want)
+		}
+		// Peeking leaves the input as it was.
+		if rest, _ := io.ReadAll(input); string(rest) != test.input {
+			t.Errorf("%q: left %q to read", test.input, rest)
+		}
+	}
+}
+
+// Maps without a known extension are read in the format they start with.
+func TestReadMapFormats(t *testing.T) {
+	dir := writeMaps(t, map[string]string{
+		"text":    threeStations + "\nconnections:\na-b\n",
+		"json":    "\n" + `{"stations": [{"name": "a", "x": 0, "y": 0}, {"name": "b", "x": 3, "y": 4}], "connections": [{"from": "a", "to": "b"}]}`,
+		"graphml": "  " + graphml("", graphmlNodes, `<edge source="a" target="b"/>`),
+	})
+	for _, file := range []string{"text", "json", "graphml"} {
+		net, err := ReadMap(filepath.Join(dir, file))
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+		if len(net.Connections) != 1 || connection(t, net, "a", "b").End.Name != "b" {
+			t.Errorf("%s: got connections %v, want a-b", file, net.Connections)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	network "stations/go/network/dijkstra"
+	"strconv"
+)
+
+// WriteMap writes the network as a text map in canonical form: the header,
+// then the stations sorted by name, then the connections sorted by their
+// stations, with two-way connections written alphabetically. Parsing the
+// output gives back the same network.
+func WriteMap(w io.Writer, net *network.Network) error {
+	out := bufio.NewWriter(w)
+
+	header := []struct{ key, value string }{
+		{HeaderName, net.Metadata.Name},
+		{HeaderAuthor, net.Metadata.Author},
+		{HeaderVersion, net.Metadata.Version},
+		{HeaderDefaultStart, net.Metadata.DefaultStart},
+		{HeaderDefaultEnd, net.Metadata.DefaultEnd},
+		{HeaderCoordinates, net.Metadata.Coordinates},
+	}
+	wroteHeader := false
+	for _, field := range header {
+		if field.value != "" {
+			fmt.Fprintf(out, "# @%s %s\n", field.key, field.value)
+			wroteHeader = true
+		}
+	}
+	if wroteHeader {
+		fmt.Fprintln(out)
+	}
+
+	fmt.Fprintln(out, "stations:")
+	for _, name := range net.StationNames() {
+		station := net.Stations[name]
+		if station.Geographic {
+			fmt.Fprintf(out, "%s,%s,%s", station.Name, formatDegrees(station.Lat), formatDegrees(station.Lon))
+		} else {
+			fmt.Fprintf(out, "%s,%d,%d", station.Name, station.X, station.Y)
+		}
+		if station.Capacity > 0 {
+			fmt.Fprintf(out, ",%d", station.Capacity)
+		}
+		fmt.Fprintln(out)
+	}
+
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "connections:")
+	for _, connection := range canonicalConnections(net) {
+		separator := "-"
+		if connection.Directed {
+			separator = "->"
+		}
+		fmt.Fprintf(out, "%s%s%s", connection.Start.Name, separator, connection.End.Name)
+		if connection.Time > 0 {
+			fmt.Fprintf(out, ",%d", connection.Time)
+		}
+		if connection.Capacity > 0 {
+			fmt.Fprintf(out, " x%d", connection.Capacity)
+		}
+		fmt.Fprintln(out)
+	}
+
+	return out.Flush()
+}
+
+// formatDegrees writes a latitude or longitude with as many digits as it needs.
+func formatDegrees(degrees float64) string {
+	return strconv.FormatFloat(degrees, 'f', -1, 64)
+}
+
+// canonicalConnections returns the connections of the network with two-way
+// connections starting at the alphabetically first station, sorted by start
+// and then end station.
+func canonicalConnections(net *network.Network) network.Connections {
+	connections := make(network.Connections, len(net.Connections))
+	copy(connections, net.Connections)
+	for i, connection := range connections {
+		if !connection.Directed && connection.End.Name < connection.Start.Name {
+			connections[i].Start, connections[i].End = connection.End, connection.Start
+		}
+	}
+	sort.SliceStable(connections, func(i, j int) bool {
+		if connections[i].Start.Name != connections[j].Start.Name {
+			return connections[i].Start.Name < connections[j].Start.Name
+		}
+		return connections[i].End.Name < connections[j].End.Name
+	})
+	return connections
+}
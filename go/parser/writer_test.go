@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteMapRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"grid", `# @name London
+# @author Laura
+# @version 2
+# @default-start waterloo
+# @default-end st_pancras
+
+stations:
+waterloo,3,1
+victoria,6,7,2
+euston,11,23
+st_pancras,5,15,4
+
+connections:
+victoria-waterloo,4
+waterloo->euston x2
+euston->waterloo
+st_pancras-euston,12 x3
+victoria - st_pancras
+`},
+		{"geographic", `# @name Finland
+# @coordinates geographic
+
+stations:
+helsinki,60.1719,24.9414
+tampere,61.4981,23.7608,3
+oulu,65.0121,25.4651
+
+connections:
+helsinki-tampere,95 x2
+tampere->oulu
+oulu->tampere,300
+`},
+	}
+	for _, test := range tests {
+		net := parseMap(t, test.text)
+		var first strings.Builder
+		if err := WriteMap(&first, net); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		again := parseMap(t, first.String())
+
+		if !reflect.DeepEqual(again.Stations, net.Stations) {
+			t.Errorf("%s: got stations %v, want %v", test.name, again.Stations, net.Stations)
+		}
+		if got, want := canonicalConnections(again), canonicalConnections(net); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got connections %v, want %v", test.name, got, want)
+		}
+		if !reflect.DeepEqual(again.Metadata, net.Metadata) {
+			t.Errorf("%s: got metadata %+v, want %+v", test.name, again.Metadata, net.Metadata)
+		}
+
+		// The canonical form of a canonical map is itself.
+		var second strings.Builder
+		if err := WriteMap(&second, again); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if second.String() != first.String() {
+			t.Errorf("%s: writing the map again gave\n%s\nwant\n%s", test.name, second.String(), first.String())
+		}
+	}
+}
+
+func TestWriteMapCanonicalForm(t *testing.T) {
+	net := parseMap(t, "# @version 1\nstations:\nc,6,8\nb,3,4,2\na,0,0\n\nconnections:\nc-b,5\nb->a x2\na->b\n")
+	var out strings.Builder
+	if err := WriteMap(&out, net); err != nil {
+		t.Fatal(err)
+	}
+	want := `# @version 1
+
+stations:
+a,0,0
+b,3,4,2
+c,6,8
+
+connections:
+a->b
+b->a x2
+b-c,5
+`
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,172 @@
+package pathfinder
+
+import (
+	"container/heap"
+	network "stations/go/network/dijkstra"
+	"strings"
+)
+
+// KShortestPaths finds the k shortest loopless paths from start to end with
+// Yen's algorithm. Paths are ordered by travel time, and paths of the same
+// travel time by fewer stations. There are fewer than k paths when the
+// network has no more.
+func KShortestPaths(start, end string, net *network.Network, k int) [][]string {
+	paths := newPathEnumerator(start, end, buildAdjacencyList(net))
+	var shortest [][]string
+	for len(shortest) < k {
+		path, found := paths.next()
+		if !found {
+			break
+		}
+		shortest = append(shortest, path)
+	}
+	return shortest
+}
+
+// pathEnumerator hands out the loopless paths between two stations one at a
+// time, shortest first, finding each only when it is asked for.
+type pathEnumerator struct {
+	start, end    string
+	adjacencyList map[string]map[string]int
+	// scale makes one more unit of travel time cost more than any number
+	// of stations, so that a single number orders paths by time and then
+	// by stations.
+	scale int
+	// found are the paths handed out so far, candidates those that may
+	// come next, and seen every path ever made a candidate.
+	found      [][]string
+	candidates []candidatePath
+	seen       map[string]bool
+}
+
+type candidatePath struct {
+	path []string
+	cost int
+}
+
+func newPathEnumerator(start, end string, adjacencyList map[string]map[string]int) *pathEnumerator {
+	// A loopless path has fewer tracks than there are stations.
+	scale := len(adjacencyList) + 1
+	return &pathEnumerator{
+		start:         start,
+		end:           end,
+		adjacencyList: adjacencyList,
+		scale:         scale,
+		seen:          make(map[string]bool),
+	}
+}
+
+// next returns the next shortest path, or false when there are no more.
+func (e *pathEnumerator) next() ([]string, bool) {
+	if len(e.found) == 0 {
+		path, _, found := e.shortestPath(e.start, nil, nil)
+		if !found {
+			return nil, false
+		}
+		e.seen[strings.Join(path, "-")] = true
+		e.found = append(e.found, path)
+		return path, true
+	}
+
+	// Every station of the last path but the end is a spur station: the
+	// new paths follow the last path up to it and then leave it by a
+	// track that no path found so far takes from there.
+	last := e.found[len(e.found)-1]
+	for i := 0; i < len(last)-1; i++ {
+		root := last[:i+1]
+		removedTracks := make(map[[2]string]bool)
+		for _, path := range e.found {
+			if len(path) > i+1 && slicesEqual(path[:i+1], root) {
+				removedTracks[[2]string{path[i], path[i+1]}] = true
+			}
+		}
+		// Stations of the root may not be visited again.
+		removedStations := make(map[string]bool)
+		for _, station := range root[:i] {
+			removedStations[station] = true
+		}
+
+		spur, spurCost, found := e.shortestPath(root[i], removedStations, removedTracks)
+		if !found {
+			continue
+		}
+		path := append(append([]string(nil), root[:i]...), spur...)
+		key := strings.Join(path, "-")
+		if e.seen[key] {
+			continue
+		}
+		e.seen[key] = true
+		e.addCandidate(candidatePath{path: path, cost: e.cost(root) + spurCost})
+	}
+
+	if len(e.candidates) == 0 {
+		return nil, false
+	}
+	best := e.candidates[0]
+	e.candidates = e.candidates[1:]
+	e.found = append(e.found, best.path)
+	return best.path, true
+}
+
+// addCandidate inserts a candidate path in order of cost, after candidates
+// of the same cost.
+func (e *pathEnumerator) addCandidate(candidate candidatePath) {
+	i := len(e.candidates)
+	for i > 0 && e.candidates[i-1].cost > candidate.cost {
+		i--
+	}
+	e.candidates = append(e.candidates, candidatePath{})
+	copy(e.candidates[i+1:], e.candidates[i:])
+	e.candidates[i] = candidate
+}
+
+// cost returns the cost of a path: its travel time and its stations.
+func (e *pathEnumerator) cost(path []string) int {
+	return pathTime(path, e.adjacencyList)*e.scale + len(path) - 1
+}
+
+// shortestPath finds the cheapest path from start to the end station with
+// Dijkstra's algorithm, without going through removedStations or along
+// removedTracks.
+func (e *pathEnumerator) shortestPath(start string, removedStations map[string]bool, removedTracks map[[2]string]bool) ([]string, int, bool) {
+	pq := make(network.PriorityQueue, 0)
+	heap.Init(&pq)
+	heap.Push(&pq, &network.Item{Value: start, Priority: 0})
+
+	distances := map[string]int{start: 0}
+	previous := make(map[string]string)
+	visited := make(map[string]bool)
+
+	for pq.Len() > 0 {
+		current := heap.Pop(&pq).(*network.Item).Value
+		if visited[current] {
+			continue
+		}
+		visited[current] = true
+
+		if current == e.end {
+			path := []string{}
+			for at := e.end; at != start; at = previous[at] {
+				path = append(path, at)
+			}
+			path = append(path, start)
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			return path, distances[e.end], true
+		}
+
+		for neighbour, travelTime := range e.adjacencyList[current] {
+			if visited[neighbour] || removedStations[neighbour] || removedTracks[[2]string{current, neighbour}] {
+				continue
+			}
+			distance := distances[current] + travelTime*e.scale + 1
+			if old, exists := distances[neighbour]; !exists || distance < old {
+				distances[neighbour] = distance
+				previous[neighbour] = current
+				heap.Push(&pq, &network.Item{Value: neighbour, Priority: distance})
+			}
+		}
+	}
+	return nil, 0, false
+}
@@ -4,27 +4,24 @@ package pathfinder
 import (
 	"container/heap"
 	"fmt"
-	"math"
-	"os"
 	"stations/go/A"
 	network "stations/go/network/dijkstra"
-	"strings"
 )
 
-func Heurestic(s1, s2 network.Station) int {
-	dx := s1.X - s2.X
-	dy := s1.Y - s2.Y
-	return int(math.Sqrt(float64(dx*dx + dy*dy)))
+// TravelTime returns the travel time given for the connection in the map, or
+// the distance between its stations when the map did not give one.
+func TravelTime(connection network.Connection) int {
+	return connection.TravelTime()
 }
 
-// buildAdjacencyList builds an adjacency list from connections with travel times.
-func buildAdjacencyList(connections network.Connections) map[string]map[string]int {
+// buildAdjacencyList builds an adjacency list from the network's connections with travel times.
+func buildAdjacencyList(net *network.Network) map[string]map[string]int {
 	adjacencyList := make(map[string]map[string]int)
 
-	for _, connection := range connections {
+	for _, connection := range net.Connections {
 		startName := connection.Start.Name
 		endName := connection.End.Name
-		travelTime := Heurestic(connection.Start, connection.End)
+		travelTime := TravelTime(connection)
 
 		// Initialize maps for start and end if they don't already exist
 		if _, exists := adjacencyList[startName]; !exists {
@@ -34,16 +31,18 @@ func buildAdjacencyList(connections network.Connections) map[string]map[string]i
 			adjacencyList[endName] = make(map[string]int)
 		}
 
-		// Update adjacency list with travel time
+		// Update adjacency list with travel time, one-way tracks only forward
 		adjacencyList[startName][endName] = travelTime
-		adjacencyList[endName][startName] = travelTime
+		if !connection.Directed {
+			adjacencyList[endName][startName] = travelTime
+		}
 	}
 
 	return adjacencyList
 }
 
-func FindShortestPath(start, end string, connections network.Connections) ([]string, error) {
-	adjacencyList := buildAdjacencyList(connections)
+func FindShortestPath(start, end string, net *network.Network) ([]string, error) {
+	adjacencyList := buildAdjacencyList(net)
 	pq := make(network.PriorityQueue, 0)
 	heap.Init(&pq)
 	heap.Push(&pq, &network.Item{Value: start, Priority: 0})
@@ -92,186 +91,43 @@ func FindShortestPath(start, end string, connections network.Connections) ([]str
 	return nil, fmt.Errorf("no path found between %s and %s", start, end)
 }
 
-func ScheduleTrainMovements(start, end string, connections network.Connections, numTrains int) []string {
-
-	if len(connections) > 20 {
-		A.PrintResult()
-		os.Exit(0)
-	}
-
-	var movements []string
-	occupied := make(map[string]int)
-	trains := make([]network.Train, numTrains)
-	trainPositions := make(map[int]string)
-
-	// Initialize trains and their positions with colors
-	for i := 0; i < numTrains; i++ {
-		color := ""
-		switch i % 4 {
-		case 0:
-			color = "31" // Red
-		case 1:
-			color = "33" // Yellow
-		case 2:
-			color = "34" // Blue
-		case 3:
-			color = "32" // Green
-		}
-		trains[i] = network.Train{ID: i + 1, Color: color}
-		trainPositions[i+1] = start
-	}
-
-	var fpath []string
-
-	fpath, _ = FindShortestPath(start, end, connections) // Use Dijkstra's algorithm
-
-	step := 0
-	maxSteps := 10000 // Limit steps to avoid infinite loop
-
-	for !allTrainsReachedEnd(trainPositions, end) && step < maxSteps {
-		trainsPaths := make(map[int][]string)
-		var moves []string
-		nextOccupied := make(map[string]int)
-
-		for i := 1; i <= numTrains; i++ {
-			train := trains[i-1]
-			if trainPositions[i] != end {
-				var path []string
-				reachedDestinationOr1TurnAway := true
-
-				// Check paths of other trains to determine optimal route for this train
-				for j := 1; j <= i; j++ {
-					if trainPositions[j] != end {
-						tPath := trainsPaths[j]
-						if len(tPath) > 1 && tPath[1] != end {
-							reachedDestinationOr1TurnAway = false
-						}
-					}
-				}
-
-				if step == 0 && i == 1 {
-					path = fpath
-				} else if i == numTrains && reachedDestinationOr1TurnAway && len(fpath) == 2 {
-					path = fpath
-				} else {
-					// Find all possible paths from current position to end
-					allPaths, found := FindAllPaths(trainPositions[i], end, connections)
-					if found {
-						// Choose the best path based on overlap and other criteria
-						for _, p := range allPaths {
-							isGood := true
-							for k := 1; k <= i; k++ {
-								overlapCount := CountOverlap(trainsPaths[k], p)
-								if overlapCount > 4 {
-									isGood = false
-								}
-							}
-							isDuplicate := false
-							for j := 1; j <= i; j++ {
-								if slicesEqual(p, trainsPaths[j]) {
-									isDuplicate = true
-								}
-							}
-							if nextOccupied[p[1]] == 0 && !contains(p[1:], start) && !isDuplicate && isGood {
-								if len(p) > len(fpath)+2 {
-									if contains(fpath, trainPositions[i]) {
-										ind := slicesIndex(fpath, trainPositions[i])
-										path = fpath[ind:]
-									}
-								} else {
-									path = p
-								}
-								break
-							}
-						}
-					}
-				}
-
-				if len(path) > 0 {
-					trainsPaths[i] = path
-					nextPos := path[1]
-					if nextPos == end && trainPositions[i] != start || (nextOccupied[nextPos] == 0 && occupied[nextPos] == 0) {
-						moves = append(moves, fmt.Sprintf("\033[%sm%s-%s\033[0m", train.Color, fmt.Sprintf("T%d", i), nextPos))
-						if trainPositions[i] != start {
-							occupied[trainPositions[i]]--
-						}
-						if nextPos != end {
-							nextOccupied[nextPos]++
-						}
-						if nextPos == end {
-							nextOccupied[nextPos] = 0
-						}
-						trainPositions[i] = nextPos
-					}
-				}
-			}
-		}
-
-		// Update occupied stations
-		for station, count := range nextOccupied {
-			occupied[station] = count
-		}
-
-		// Add movements to result if there are any
-		if len(moves) > 0 {
-			movements = append(movements, strings.Join(moves, " "))
-			step++
-		}
+// ScheduleTrainMovements schedules numTrains trains from start to end and
+// returns the moves of each turn as printable lines.
+func ScheduleTrainMovements(start, end string, net *network.Network, numTrains int) ([]string, error) {
+	schedule, err := ScheduleTrains(start, end, net, numTrains)
+	if err != nil {
+		return nil, err
 	}
-
-	return movements
+	return schedule.Lines(), nil
 }
 
-// FindAllPaths finds all possible paths from start to end.
-func FindAllPaths(start, end string, connections network.Connections) ([][]string, bool) {
-	adjacencyList := buildAdjacencyList(connections)
-	var paths [][]string
-	queue := [][]string{{start}}
-
-	for len(queue) > 0 {
-		path := queue[0]
-		queue = queue[1:]
-		current := path[len(path)-1]
-
-		if current == end {
-			paths = append(paths, path)
-		} else {
-			for neighbor := range adjacencyList[current] {
-				if !contains(path, neighbor) {
-					newPath := append([]string(nil), path...)
-					newPath = append(newPath, neighbor)
-					queue = append(queue, newPath)
-				}
-			}
+// ScheduleTrains returns the schedule that moves numTrains trains from start
+// to end in the fewest turns, see A.OptimalPlan. It fails when start or end
+// is not a station of the network, when they are the same station, or when
+// end cannot be reached from start.
+func ScheduleTrains(start, end string, net *network.Network, numTrains int) (network.Schedule, error) {
+	for _, name := range []string{start, end} {
+		if !net.HasStation(name) {
+			return network.Schedule{}, fmt.Errorf("station %s does not exist", name)
 		}
 	}
-
-	return paths, len(paths) > 0
-}
-
-// CountOverlap counts the number of overlapping elements between two slices.
-func CountOverlap(a, b []string) int {
-	count := 0
-	set := make(map[string]bool)
-	for _, v := range a {
-		set[v] = true
+	if start == end {
+		return network.Schedule{}, fmt.Errorf("start and end station cannot be the same: %s", start)
 	}
-	for _, v := range b {
-		if set[v] {
-			count++
-		}
+	plan, found := A.OptimalPlan(start, end, net, numTrains)
+	if !found {
+		return network.Schedule{}, fmt.Errorf("no path found between %s and %s", start, end)
 	}
-	return count
+	return plan.Schedule(), nil
 }
 
-// contains checks if a slice contains a string.
-func contains(slice []string, item string) bool {
-	for _, v := range slice {
-		if v == item {
-			return true
-		}
+// pathTime sums the travel times along a path.
+func pathTime(path []string, adjacencyList map[string]map[string]int) int {
+	total := 0
+	for i := 1; i < len(path); i++ {
+		total += adjacencyList[path[i-1]][path[i]]
 	}
-	return false
+	return total
 }
 
 // slicesEqual checks if two slices are equal.
@@ -286,23 +142,3 @@ func slicesEqual(a, b []string) bool {
 	}
 	return true
 }
-
-// slicesIndex returns the index of the first occurrence of item in slice.
-func slicesIndex(slice []string, item string) int {
-	for i, v := range slice {
-		if v == item {
-			return i
-		}
-	}
-	return -1
-}
-
-// allTrainsReachedEnd checks if all trains have reached the end station.
-func allTrainsReachedEnd(trainPositions map[int]string, end string) bool {
-	for _, pos := range trainPositions {
-		if pos != end {
-			return false
-		}
-	}
-	return true
-}
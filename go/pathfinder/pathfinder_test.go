@@ -0,0 +1,136 @@
+package pathfinder
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	network "stations/go/network/dijkstra"
+	"stations/go/parser"
+	"strings"
+	"testing"
+)
+
+// parseMap parses a text map that has to be valid.
+func parseMap(t *testing.T, text string) *network.Network {
+	t.Helper()
+	net, err := parser.ParseNetwork(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("parsing map: %v", err)
+	}
+	return net
+}
+
+func TestFindShortestPathUsesTravelTimes(t *testing.T) {
+	const stations = `stations:
+a,0,0
+b,1,5
+c,2,0
+
+connections:
+`
+	tests := []struct {
+		name        string
+		connections string
+		want        []string
+	}{
+		// a-c is the shorter line, but slower than going through b.
+		{"times", "a-c,10\na-b,2\nb-c,3\n", []string{"a", "b", "c"}},
+		{"distances", "a-c\na-b\nb-c\n", []string{"a", "c"}},
+		{"time against distance", "a-c,9\na-b\nb-c\n", []string{"a", "c"}},
+	}
+	for _, test := range tests {
+		net := parseMap(t, stations+test.connections)
+		got, err := FindShortestPath("a", "c", net)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// allPaths enumerates every loopless path from start to end, in the order
+// KShortestPaths promises: travel time, then stations.
+func allPaths(start, end string, adjacencyList map[string]map[string]int) [][]string {
+	var paths [][]string
+	var walk func(path []string, visited map[string]bool)
+	walk = func(path []string, visited map[string]bool) {
+		current := path[len(path)-1]
+		if current == end {
+			paths = append(paths, append([]string(nil), path...))
+			return
+		}
+		for neighbor := range adjacencyList[current] {
+			if !visited[neighbor] {
+				visited[neighbor] = true
+				walk(append(path, neighbor), visited)
+				visited[neighbor] = false
+			}
+		}
+	}
+	walk([]string{start}, map[string]bool{start: true})
+
+	sort.SliceStable(paths, func(i, j int) bool {
+		ti, tj := pathTime(paths[i], adjacencyList), pathTime(paths[j], adjacencyList)
+		if ti != tj {
+			return ti < tj
+		}
+		return len(paths[i]) < len(paths[j])
+	})
+	return paths
+}
+
+func TestKShortestPathsMatchesAllPaths(t *testing.T) {
+	net, err := parser.ReadMap(filepath.Join("..", "..", "maps", "07small.txt"))
+	if err != nil {
+		t.Fatalf("reading map: %v", err)
+	}
+	adjacencyList := buildAdjacencyList(net)
+
+	all := allPaths("small", "large", adjacencyList)
+	if len(all) == 0 {
+		t.Fatal("found no path")
+	}
+
+	const k = 20
+	shortest := KShortestPaths("small", "large", net, k)
+	if len(shortest) != k {
+		t.Fatalf("got %d paths, want %d", len(shortest), k)
+	}
+	seen := make(map[string]bool)
+	for i, path := range shortest {
+		key := strings.Join(path, " ")
+		if seen[key] {
+			t.Errorf("path %d, %s, found twice", i+1, key)
+		}
+		seen[key] = true
+		// Paths that tie may come in either order, so only what makes
+		// them the i-th shortest has to match.
+		got := [2]int{pathTime(path, adjacencyList), len(path)}
+		want := [2]int{pathTime(all[i], adjacencyList), len(all[i])}
+		if got != want {
+			t.Errorf("path %d: got %s with time %d and %d stations, want time %d and %d stations like %s",
+				i+1, key, got[0], got[1], want[0], want[1], strings.Join(all[i], " "))
+		}
+	}
+}
+
+func TestScheduleTrainsBetweenInvalidStations(t *testing.T) {
+	net := parseMap(t, "stations:\na,0,0\nb,1,1\nd,2,0\n\nconnections:\na-b\nb-d\n")
+	tests := []struct {
+		name       string
+		start, end string
+		want       string
+	}{
+		{"unknown start", "zzz", "d", "station zzz does not exist"},
+		{"unknown end", "a", "zzz", "station zzz does not exist"},
+		{"same station", "d", "d", "start and end station cannot be the same: d"},
+	}
+	for _, test := range tests {
+		schedule, err := ScheduleTrains(test.start, test.end, net, 2)
+		if err == nil || err.Error() != test.want {
+			t.Errorf("%s: got %v with turns %v, want error %q", test.name, err, schedule.Turns, test.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	network "stations/go/network/dijkstra"
+	"strings"
+)
+
+// WriteAnimatedSVG draws the network like WriteSVG and plays the schedule
+// back on it with SMIL animation: every train is a marker in its colour that
+// moves to its next station in each turn, taking secondsPerTurn seconds per
+// turn. The playback shows the turn number, holds the last turn for one turn
+// and then starts over.
+func WriteAnimatedSVG(w io.Writer, net *network.Network, schedule network.Schedule, secondsPerTurn float64) error {
+	if secondsPerTurn <= 0 {
+		secondsPerTurn = 1
+	}
+	out := bufio.NewWriter(w)
+	layout := newSVGLayout(net)
+	width, height := svgCanvas(layout, &schedule)
+
+	writeSVGMap(out, net, &schedule, layout, width, height)
+	writeSVGStations(out, net, &schedule, layout)
+	writeSVGLegend(out, net, &schedule, layout)
+
+	// Frame t is where the trains are after t turns, the last frame
+	// repeats the end of the schedule before the playback starts over.
+	turns := len(schedule.Turns)
+	frames := turns + 2
+	duration := fmt.Sprintf("%gs", float64(frames-1)*secondsPerTurn)
+	keyTimes := make([]string, frames)
+	for t := range keyTimes {
+		keyTimes[t] = fmt.Sprintf("%.4f", float64(t)/float64(frames-1))
+	}
+
+	positions := make(map[int]string)
+	for _, train := range schedule.Trains {
+		positions[train.ID] = schedule.Start
+	}
+	xs := make(map[int][]string)
+	ys := make(map[int][]string)
+	record := func() {
+		for i, train := range schedule.Trains {
+			// Trains in the same station are drawn next to each other.
+			dx := float64(i%4)*4 - 6
+			dy := float64(i/4%4)*4 - 6
+			x, y := layout.point(net.Stations[positions[train.ID]])
+			xs[train.ID] = append(xs[train.ID], fmt.Sprintf("%.1f", x+dx))
+			ys[train.ID] = append(ys[train.ID], fmt.Sprintf("%.1f", y+dy))
+		}
+	}
+	record()
+	for _, turn := range schedule.Turns {
+		for _, move := range turn {
+			positions[move.Train.ID] = move.To
+		}
+		record()
+	}
+	record()
+
+	for _, train := range schedule.Trains {
+		fmt.Fprintf(out, "  <circle r=\"5\" fill=\"%s\" stroke=\"#ffffff\" stroke-width=\"1\" cx=\"%s\" cy=\"%s\">\n",
+			svgColors[train.ColorName()], xs[train.ID][0], ys[train.ID][0])
+		fmt.Fprintf(out, "    <title>T%d</title>\n", train.ID)
+		for _, attribute := range []struct {
+			name   string
+			values []string
+		}{{"cx", xs[train.ID]}, {"cy", ys[train.ID]}} {
+			fmt.Fprintf(out, "    <animate attributeName=\"%s\" values=\"%s\" keyTimes=\"%s\" dur=\"%s\" repeatCount=\"indefinite\"/>\n",
+				attribute.name, strings.Join(attribute.values, ";"), strings.Join(keyTimes, ";"), duration)
+		}
+		fmt.Fprintln(out, "  </circle>")
+	}
+
+	// One caption per turn, each visible only while its moves are shown.
+	x, y := svgMargin, height-12
+	for t := 0; t < frames-1; t++ {
+		caption := fmt.Sprintf("Turn %d / %d", t+1, turns)
+		if t == turns {
+			caption = fmt.Sprintf("All trains arrived after %d turns", turns)
+		}
+		values, times := "hidden;visible;hidden", fmt.Sprintf("0;%s;%s", keyTimes[t], keyTimes[t+1])
+		if t == 0 {
+			values, times = "visible;hidden", fmt.Sprintf("0;%s", keyTimes[1])
+		}
+		fmt.Fprintf(out, "  <text x=\"%.0f\" y=\"%.0f\" font-weight=\"bold\" visibility=\"hidden\">%s\n", x, y, caption)
+		fmt.Fprintf(out, "    <animate attributeName=\"visibility\" values=\"%s\" keyTimes=\"%s\" calcMode=\"discrete\" dur=\"%s\" repeatCount=\"indefinite\"/>\n",
+			values, times, duration)
+		fmt.Fprintln(out, "  </text>")
+	}
+
+	fmt.Fprintln(out, "</svg>")
+	return out.Flush()
+}
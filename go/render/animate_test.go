@@ -0,0 +1,130 @@
+package render
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestWriteAnimatedSVG(t *testing.T) {
+	net := testNetwork()
+	schedule := testSchedule()
+	var out strings.Builder
+	if err := WriteAnimatedSVG(&out, net, *schedule, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	root := parseSVG(t, out.String())
+	stations := stationPoints(t, root)
+
+	// The playback shows the start, every turn and the end once more, half
+	// a second each.
+	turns := len(schedule.Turns)
+	frames := turns + 2
+	wantDuration := fmt.Sprintf("%gs", float64(frames-1)*0.5)
+
+	var markers []svgElement
+	for _, circle := range root.find("circle", "") {
+		if circle.attr("class") == "" {
+			markers = append(markers, circle)
+		}
+	}
+	if len(markers) != len(schedule.Trains) {
+		t.Fatalf("got %d train markers, want %d", len(markers), len(schedule.Trains))
+	}
+
+	// Where each train is after every frame.
+	at := map[int]string{}
+	positions := map[int][]string{}
+	for _, train := range schedule.Trains {
+		at[train.ID] = schedule.Start
+		positions[train.ID] = []string{schedule.Start}
+	}
+	for _, turn := range schedule.Turns {
+		for _, move := range turn {
+			at[move.Train.ID] = move.To
+		}
+		for _, train := range schedule.Trains {
+			positions[train.ID] = append(positions[train.ID], at[train.ID])
+		}
+	}
+	for _, train := range schedule.Trains {
+		positions[train.ID] = append(positions[train.ID], at[train.ID])
+	}
+
+	for i, train := range schedule.Trains {
+		marker := markers[i]
+		if marker.title() != fmt.Sprintf("T%d", train.ID) {
+			t.Errorf("marker %d: got %s, want T%d", i+1, marker.title(), train.ID)
+		}
+		animations := marker.find("animate", "")
+		if len(animations) != 2 {
+			t.Fatalf("T%d: got %d animations, want cx and cy", train.ID, len(animations))
+		}
+		var xs, ys []string
+		for _, animation := range animations {
+			if animation.attr("dur") != wantDuration {
+				t.Errorf("T%d: got dur %s, want %s", train.ID, animation.attr("dur"), wantDuration)
+			}
+			if keyTimes := strings.Split(animation.attr("keyTimes"), ";"); len(keyTimes) != frames {
+				t.Errorf("T%d: got %d key times, want %d", train.ID, len(keyTimes), frames)
+			}
+			values := strings.Split(animation.attr("values"), ";")
+			switch animation.attr("attributeName") {
+			case "cx":
+				xs = values
+			case "cy":
+				ys = values
+			}
+		}
+		if len(xs) != frames || len(ys) != frames {
+			t.Fatalf("T%d: got %d x and %d y values, want %d", train.ID, len(xs), len(ys), frames)
+		}
+		// Markers are drawn a few pixels off their station, so that
+		// trains in one station do not hide each other.
+		for frame, station := range positions[train.ID] {
+			var x, y float64
+			fmt.Sscan(xs[frame], &x)
+			fmt.Sscan(ys[frame], &y)
+			point := stations[station]
+			if math.Abs(x-point[0]) > 6 || math.Abs(y-point[1]) > 6 {
+				t.Errorf("T%d, frame %d: drawn at %g,%g, want next to %s at %v", train.ID, frame, x, y, station, point)
+			}
+		}
+	}
+
+	// One caption per turn and one for the end, all as long as the
+	// playback.
+	var captions []svgElement
+	for _, text := range root.find("text", "") {
+		if strings.HasPrefix(strings.TrimSpace(text.Text), "Turn ") || strings.HasPrefix(strings.TrimSpace(text.Text), "All trains") {
+			captions = append(captions, text)
+		}
+	}
+	if len(captions) != turns+1 {
+		t.Fatalf("got %d captions, want %d", len(captions), turns+1)
+	}
+	for i, caption := range captions {
+		want := fmt.Sprintf("Turn %d / %d", i+1, turns)
+		if i == turns {
+			want = fmt.Sprintf("All trains arrived after %d turns", turns)
+		}
+		if got := strings.TrimSpace(caption.Text); got != want {
+			t.Errorf("caption %d: got %q, want %q", i+1, got, want)
+		}
+		animations := caption.find("animate", "")
+		if len(animations) != 1 || animations[0].attr("dur") != wantDuration {
+			t.Errorf("caption %q: want one animation of %s", want, wantDuration)
+			continue
+		}
+		// The caption of turn i shows from frame i to frame i+1.
+		keyTime := func(frame int) string { return fmt.Sprintf("%.4f", float64(frame)/float64(frames-1)) }
+		wantTimes := "0;" + keyTime(i) + ";" + keyTime(i+1)
+		if i == 0 {
+			wantTimes = "0;" + keyTime(1)
+		}
+		if got := animations[0].attr("keyTimes"); got != wantTimes {
+			t.Errorf("caption %q: got key times %s, want %s", want, got, wantTimes)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	network "stations/go/network/dijkstra"
+	"strconv"
+	"strings"
+)
+
+// WriteDOT writes the network as a Graphviz graph with every station pinned
+// to its map coordinates, for drawing with "dot -Kneato". When schedule is
+// not nil, each connection a train used is drawn in the colour of the train,
+// one line per train, and the start and end stations are highlighted.
+func WriteDOT(w io.Writer, net *network.Network, schedule *network.Schedule) error {
+	out := bufio.NewWriter(w)
+	used := usedTracks(net, schedule)
+
+	name := net.Metadata.Name
+	if name == "" {
+		name = "network"
+	}
+	fmt.Fprintf(out, "digraph %s {\n", strconv.Quote(name))
+	fmt.Fprintln(out, "  node [shape=circle, fontsize=10];")
+	fmt.Fprintln(out, "  edge [fontsize=9];")
+
+	project := net.Projection()
+	for _, name := range net.StationNames() {
+		station := net.Stations[name]
+		// Graphviz puts y upwards, maps put it downwards.
+		x, y := project(station)
+		x, y = math.Round(x*1000)/1000, -math.Round(y*1000)/1000
+		if y == 0 {
+			y = 0 // not -0
+		}
+		attributes := []string{fmt.Sprintf("pos=\"%g,%g!\"", x, y)}
+		if schedule != nil && (station.Name == schedule.Start || station.Name == schedule.End) {
+			attributes = append(attributes, "shape=doublecircle", "style=bold")
+		}
+		if station.Capacity > 1 {
+			attributes = append(attributes, fmt.Sprintf("xlabel=\"holds %d\"", station.Capacity))
+		}
+		fmt.Fprintf(out, "  %s [%s];\n", strconv.Quote(station.Name), strings.Join(attributes, ", "))
+	}
+
+	for i, connection := range net.Connections {
+		var attributes []string
+		if !connection.Directed {
+			attributes = append(attributes, "dir=none")
+		}
+
+		var label []string
+		if connection.Time > 0 {
+			label = append(label, strconv.Itoa(connection.Time))
+		}
+		if connection.Capacity > 0 {
+			label = append(label, fmt.Sprintf("x%d", connection.Capacity))
+		}
+		if len(label) > 0 {
+			attributes = append(attributes, fmt.Sprintf("label=\"%s\"", strings.Join(label, " ")))
+		}
+
+		if trains := used[i]; len(trains) > 0 {
+			colors := make([]string, len(trains))
+			ids := make([]string, len(trains))
+			for j, train := range trains {
+				colors[j] = train.ColorName()
+				ids[j] = fmt.Sprintf("T%d", train.ID)
+			}
+			attributes = append(attributes,
+				fmt.Sprintf("color=\"%s\"", strings.Join(colors, ":")),
+				"penwidth=2",
+				fmt.Sprintf("tooltip=\"%s\"", strings.Join(ids, " ")))
+		} else if schedule != nil {
+			attributes = append(attributes, "color=gray")
+		}
+
+		fmt.Fprintf(out, "  %s -> %s", strconv.Quote(connection.Start.Name), strconv.Quote(connection.End.Name))
+		if len(attributes) > 0 {
+			fmt.Fprintf(out, " [%s]", strings.Join(attributes, ", "))
+		}
+		fmt.Fprintln(out, ";")
+	}
+
+	fmt.Fprintln(out, "}")
+	return out.Flush()
+}
@@ -0,0 +1,79 @@
+package render
+
+import (
+	network "stations/go/network/dijkstra"
+	"strings"
+	"testing"
+)
+
+// testNetwork returns a small network whose station names and map name
+// need quoting: a -> king's cross -> say "hi", with a shortcut a -> say "hi".
+func testNetwork() *network.Network {
+	net := network.NewNetwork()
+	net.Metadata.Name = `London "Underground"`
+	a := network.Station{Name: "a", X: 0, Y: 0}
+	cross := network.Station{Name: "king's cross", X: 3, Y: 4, Capacity: 2}
+	hi := network.Station{Name: `say "hi"`, X: 6, Y: 0}
+	for _, station := range []network.Station{hi, cross, a} {
+		net.AddStation(station)
+	}
+	net.AddConnection(network.Connection{Start: a, End: cross, Time: 5, Capacity: 2})
+	net.AddConnection(network.Connection{Start: cross, End: hi, Directed: true})
+	net.AddConnection(network.Connection{Start: a, End: hi})
+	return net
+}
+
+// testSchedule returns the schedule of two trains over testNetwork, T1
+// along the shortcut and T2 through king's cross.
+func testSchedule() *network.Schedule {
+	t1, t2 := network.NewTrain(1), network.NewTrain(2)
+	return &network.Schedule{
+		Start:  "a",
+		End:    `say "hi"`,
+		Trains: []network.Train{t1, t2},
+		Turns: [][]network.Move{
+			{{Train: t1, From: "a", To: `say "hi"`}, {Train: t2, From: "a", To: "king's cross"}},
+			{{Train: t2, From: "king's cross", To: `say "hi"`}},
+		},
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule *network.Schedule
+		want     string
+	}{
+		{"map", nil, `digraph "London \"Underground\"" {
+  node [shape=circle, fontsize=10];
+  edge [fontsize=9];
+  "a" [pos="0,0!"];
+  "king's cross" [pos="3,-4!", xlabel="holds 2"];
+  "say \"hi\"" [pos="6,0!"];
+  "a" -> "king's cross" [dir=none, label="5 x2"];
+  "king's cross" -> "say \"hi\"";
+  "a" -> "say \"hi\"" [dir=none];
+}
+`},
+		{"schedule", testSchedule(), `digraph "London \"Underground\"" {
+  node [shape=circle, fontsize=10];
+  edge [fontsize=9];
+  "a" [pos="0,0!", shape=doublecircle, style=bold];
+  "king's cross" [pos="3,-4!", xlabel="holds 2"];
+  "say \"hi\"" [pos="6,0!", shape=doublecircle, style=bold];
+  "a" -> "king's cross" [dir=none, label="5 x2", color="yellow", penwidth=2, tooltip="T2"];
+  "king's cross" -> "say \"hi\"" [color="yellow", penwidth=2, tooltip="T2"];
+  "a" -> "say \"hi\"" [dir=none, color="red", penwidth=2, tooltip="T1"];
+}
+`},
+	}
+	for _, test := range tests {
+		var out strings.Builder
+		if err := WriteDOT(&out, testNetwork(), test.schedule); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if out.String() != test.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.name, out.String(), test.want)
+		}
+	}
+}
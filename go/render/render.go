@@ -0,0 +1,41 @@
+package render
+
+import (
+	network "stations/go/network/dijkstra"
+)
+
+// usedTracks returns, for every connection of the network by index, the
+// trains of the schedule that travelled along it, in the order of the
+// trains. A nil schedule uses no connections.
+func usedTracks(net *network.Network, schedule *network.Schedule) map[int][]network.Train {
+	used := make(map[int][]network.Train)
+	if schedule == nil {
+		return used
+	}
+
+	index := make(map[[2]string]int)
+	for i, connection := range net.Connections {
+		from, to := connection.Start.Name, connection.End.Name
+		index[[2]string{from, to}] = i
+		if !connection.Directed {
+			if _, exists := index[[2]string{to, from}]; !exists {
+				index[[2]string{to, from}] = i
+			}
+		}
+	}
+
+	paths := schedule.Paths()
+	seen := make(map[[2]int]bool)
+	for _, train := range schedule.Trains {
+		path := paths[train.ID]
+		for i := 1; i < len(path); i++ {
+			connection, exists := index[[2]string{path[i-1], path[i]}]
+			if !exists || seen[[2]int{connection, train.ID}] {
+				continue
+			}
+			seen[[2]int{connection, train.ID}] = true
+			used[connection] = append(used[connection], train)
+		}
+	}
+	return used
+}
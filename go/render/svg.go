@@ -0,0 +1,195 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	network "stations/go/network/dijkstra"
+	"strings"
+)
+
+// svgColors are the colours of the trains in pictures, by colour name.
+var svgColors = map[string]string{
+	"red":    "#d62728",
+	"yellow": "#e6b800",
+	"blue":   "#1f77b4",
+	"green":  "#2ca02c",
+	"black":  "#000000",
+}
+
+// svgLayout places map coordinates in a picture.
+type svgLayout struct {
+	// project gives the plane coordinates of a station, see
+	// network.Network.Projection.
+	project    func(network.Station) (float64, float64)
+	minX, minY float64
+	scale      float64
+	// mapWidth and mapHeight are the size of the drawn map in pixels.
+	mapWidth, mapHeight float64
+}
+
+const (
+	svgMargin     = 40.0
+	svgMapSize    = 600.0
+	svgLegendLine = 20.0
+)
+
+// newSVGLayout scales the stations of the network so that the longer side
+// of the map is svgMapSize pixels.
+func newSVGLayout(net *network.Network) svgLayout {
+	project := net.Projection()
+	first := true
+	var minX, minY, maxX, maxY float64
+	for _, station := range net.Stations {
+		x, y := project(station)
+		if first || x < minX {
+			minX = x
+		}
+		if first || y < minY {
+			minY = y
+		}
+		if first || x > maxX {
+			maxX = x
+		}
+		if first || y > maxY {
+			maxY = y
+		}
+		first = false
+	}
+	span := math.Max(math.Max(maxX-minX, maxY-minY), 1e-9)
+	scale := svgMapSize / span
+	return svgLayout{
+		project:   project,
+		minX:      minX,
+		minY:      minY,
+		scale:     scale,
+		mapWidth:  (maxX - minX) * scale,
+		mapHeight: (maxY - minY) * scale,
+	}
+}
+
+// point returns where a station is drawn.
+func (l svgLayout) point(station network.Station) (float64, float64) {
+	x, y := l.project(station)
+	return svgMargin + (x-l.minX)*l.scale, svgMargin + (y-l.minY)*l.scale
+}
+
+// WriteSVG draws the network as a self-contained SVG picture: the stations
+// at their map coordinates, the connections, and when schedule is not nil
+// the route of every train in its colour, the start and end stations, and a
+// legend with one line per train.
+func WriteSVG(w io.Writer, net *network.Network, schedule *network.Schedule) error {
+	out := bufio.NewWriter(w)
+	layout := newSVGLayout(net)
+	width, height := svgCanvas(layout, schedule)
+
+	writeSVGMap(out, net, schedule, layout, width, height)
+
+	if schedule != nil {
+		paths := schedule.Paths()
+		for i, train := range schedule.Trains {
+			// Trains on the same connection are drawn side by side.
+			offset := (float64(i%8) - 3.5) * 1.5
+			var points []string
+			for _, name := range paths[train.ID] {
+				x, y := layout.point(net.Stations[name])
+				points = append(points, fmt.Sprintf("%.1f,%.1f", x+offset, y+offset))
+			}
+			fmt.Fprintf(out, "  <polyline class=\"train\" points=\"%s\" stroke=\"%s\"><title>T%d</title></polyline>\n",
+				strings.Join(points, " "), svgColors[train.ColorName()], train.ID)
+		}
+	}
+
+	writeSVGStations(out, net, schedule, layout)
+
+	if schedule != nil {
+		writeSVGLegend(out, net, schedule, layout)
+	}
+
+	fmt.Fprintln(out, "</svg>")
+	return out.Flush()
+}
+
+// svgCanvas returns the size of the picture: the map, and to its right the
+// legend when there is a schedule.
+func svgCanvas(layout svgLayout, schedule *network.Schedule) (float64, float64) {
+	width := layout.mapWidth + 2*svgMargin
+	height := layout.mapHeight + 2*svgMargin
+	if schedule != nil {
+		width += 320
+		height = math.Max(height, svgMargin+float64(len(schedule.Trains)+2)*svgLegendLine)
+	}
+	return width, height
+}
+
+// writeSVGMap opens the picture and draws the title and the connections.
+func writeSVGMap(out *bufio.Writer, net *network.Network, schedule *network.Schedule, layout svgLayout, width, height float64) {
+	fmt.Fprintf(out, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%.0f\" height=\"%.0f\" viewBox=\"0 0 %.0f %.0f\" font-family=\"sans-serif\" font-size=\"12\">\n",
+		width, height, width, height)
+	fmt.Fprintln(out, "  <defs>")
+	fmt.Fprintln(out, "    <marker id=\"arrow\" viewBox=\"0 0 10 10\" refX=\"18\" refY=\"5\" markerWidth=\"6\" markerHeight=\"6\" orient=\"auto\">")
+	fmt.Fprintln(out, "      <path d=\"M0,0 L10,5 L0,10 z\" fill=\"#999999\"/>")
+	fmt.Fprintln(out, "    </marker>")
+	fmt.Fprintln(out, "  </defs>")
+	fmt.Fprintln(out, "  <style>")
+	fmt.Fprintln(out, "    .connection { stroke: #999999; stroke-width: 2; }")
+	fmt.Fprintln(out, "    .train { fill: none; stroke-width: 3; stroke-opacity: 0.8; stroke-linejoin: round; }")
+	fmt.Fprintln(out, "    .station { fill: #ffffff; stroke: #333333; stroke-width: 2; }")
+	fmt.Fprintln(out, "    .terminal { fill: #333333; }")
+	fmt.Fprintln(out, "  </style>")
+	fmt.Fprintf(out, "  <rect width=\"100%%\" height=\"100%%\" fill=\"#ffffff\"/>\n")
+
+	if title := net.Metadata.Name; title != "" {
+		fmt.Fprintf(out, "  <text x=\"%.0f\" y=\"20\" font-size=\"16\" font-weight=\"bold\">%s</text>\n", svgMargin, html.EscapeString(title))
+	}
+
+	for _, connection := range net.Connections {
+		x1, y1 := layout.point(net.Stations[connection.Start.Name])
+		x2, y2 := layout.point(net.Stations[connection.End.Name])
+		marker := ""
+		if connection.Directed {
+			marker = " marker-end=\"url(#arrow)\""
+		}
+		width := ""
+		if connection.Capacity > 1 {
+			width = fmt.Sprintf(" style=\"stroke-width: %d\"", 2*connection.Capacity)
+		}
+		fmt.Fprintf(out, "  <line class=\"connection\" x1=\"%.1f\" y1=\"%.1f\" x2=\"%.1f\" y2=\"%.1f\"%s%s/>\n", x1, y1, x2, y2, marker, width)
+	}
+}
+
+// writeSVGStations draws the stations with their names, the start and end
+// station of the schedule filled and labelled.
+func writeSVGStations(out *bufio.Writer, net *network.Network, schedule *network.Schedule, layout svgLayout) {
+	for _, name := range net.StationNames() {
+		x, y := layout.point(net.Stations[name])
+		class := "station"
+		label := name
+		if schedule != nil && name == schedule.Start {
+			class, label = "station terminal", "start: "+name
+		} else if schedule != nil && name == schedule.End {
+			class, label = "station terminal", "end: "+name
+		}
+		fmt.Fprintf(out, "  <circle class=\"%s\" cx=\"%.1f\" cy=\"%.1f\" r=\"6\"><title>%s</title></circle>\n", class, x, y, html.EscapeString(name))
+		fmt.Fprintf(out, "  <text x=\"%.1f\" y=\"%.1f\">%s</text>\n", x+9, y-9, html.EscapeString(label))
+	}
+}
+
+// writeSVGLegend lists every train with its colour and its route.
+func writeSVGLegend(out *bufio.Writer, net *network.Network, schedule *network.Schedule, layout svgLayout) {
+	x := layout.mapWidth + 2*svgMargin + 20
+	y := svgMargin
+	fmt.Fprintf(out, "  <text x=\"%.0f\" y=\"%.0f\" font-weight=\"bold\">%d trains, %d turns</text>\n", x, y, len(schedule.Trains), len(schedule.Turns))
+
+	paths := schedule.Paths()
+	for i, train := range schedule.Trains {
+		y := svgMargin + float64(i+1)*svgLegendLine
+		route := paths[train.ID]
+		fmt.Fprintf(out, "  <line x1=\"%.0f\" y1=\"%.0f\" x2=\"%.0f\" y2=\"%.0f\" stroke=\"%s\" stroke-width=\"4\"/>\n",
+			x, y-4, x+20, y-4, svgColors[train.ColorName()])
+		fmt.Fprintf(out, "  <text x=\"%.0f\" y=\"%.0f\">T%d: %s</text>\n",
+			x+28, y, train.ID, html.EscapeString(strings.Join(route, " → ")))
+	}
+}
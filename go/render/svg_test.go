@@ -0,0 +1,198 @@
+package render
+
+import (
+	"encoding/xml"
+	"math"
+	network "stations/go/network/dijkstra"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// svgElement is an element of an SVG picture with everything in it.
+type svgElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr   `xml:",any,attr"`
+	Text     string       `xml:",chardata"`
+	Children []svgElement `xml:",any"`
+}
+
+// attr returns the value of the attribute name, or "" when there is none.
+func (e svgElement) attr(name string) string {
+	for _, attr := range e.Attrs {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// number returns the attribute name as a number.
+func (e svgElement) number(t *testing.T, name string) float64 {
+	t.Helper()
+	value, err := strconv.ParseFloat(e.attr(name), 64)
+	if err != nil {
+		t.Fatalf("<%s %s=%q>: %v", e.XMLName.Local, name, e.attr(name), err)
+	}
+	return value
+}
+
+// title returns the text of the title element in e.
+func (e svgElement) title() string {
+	for _, child := range e.Children {
+		if child.XMLName.Local == "title" {
+			return child.Text
+		}
+	}
+	return ""
+}
+
+// find returns the elements named name in e and below it whose class starts
+// with class, in the order of the picture.
+func (e svgElement) find(name, class string) []svgElement {
+	var found []svgElement
+	for _, child := range e.Children {
+		if child.XMLName.Local == name && strings.HasPrefix(child.attr("class"), class) {
+			found = append(found, child)
+		}
+		found = append(found, child.find(name, class)...)
+	}
+	return found
+}
+
+// parseSVG parses a picture that has to be well-formed XML.
+func parseSVG(t *testing.T, picture string) svgElement {
+	t.Helper()
+	var root svgElement
+	if err := xml.Unmarshal([]byte(picture), &root); err != nil {
+		t.Fatalf("parsing SVG: %v\n%s", err, picture)
+	}
+	if root.XMLName.Local != "svg" {
+		t.Fatalf("got root element %s, want svg", root.XMLName.Local)
+	}
+	return root
+}
+
+// drawSVG draws the network with WriteSVG and parses the picture.
+func drawSVG(t *testing.T, net *network.Network, schedule *network.Schedule) svgElement {
+	t.Helper()
+	var out strings.Builder
+	if err := WriteSVG(&out, net, schedule); err != nil {
+		t.Fatal(err)
+	}
+	return parseSVG(t, out.String())
+}
+
+// stationPoints returns where the picture draws each station, by name.
+func stationPoints(t *testing.T, root svgElement) map[string][2]float64 {
+	t.Helper()
+	points := make(map[string][2]float64)
+	for _, circle := range root.find("circle", "station") {
+		points[circle.title()] = [2]float64{circle.number(t, "cx"), circle.number(t, "cy")}
+	}
+	return points
+}
+
+func TestWriteSVG(t *testing.T) {
+	net := testNetwork()
+	for _, schedule := range []*network.Schedule{nil, testSchedule()} {
+		root := drawSVG(t, net, schedule)
+
+		var names []string
+		for _, circle := range root.find("circle", "station") {
+			names = append(names, circle.title())
+		}
+		if want := net.StationNames(); strings.Join(names, ",") != strings.Join(want, ",") {
+			t.Errorf("got stations %q, want %q", names, want)
+		}
+		terminals := root.find("circle", "station terminal")
+		connections := root.find("line", "connection")
+		if len(connections) != len(net.Connections) {
+			t.Errorf("got %d connections, want %d", len(connections), len(net.Connections))
+		}
+		// Only the one-way track has an arrow, only the double track is wider.
+		var arrows, wide int
+		for _, line := range connections {
+			if line.attr("marker-end") != "" {
+				arrows++
+			}
+			if line.attr("style") != "" {
+				wide++
+			}
+		}
+		if arrows != 1 || wide != 1 {
+			t.Errorf("got %d arrows and %d wide tracks, want 1 and 1", arrows, wide)
+		}
+
+		trains := root.find("polyline", "train")
+		if schedule == nil {
+			if len(terminals) != 0 || len(trains) != 0 {
+				t.Errorf("without a schedule: got %d terminals and %d trains, want none", len(terminals), len(trains))
+			}
+			continue
+		}
+		if len(terminals) != 2 {
+			t.Errorf("got %d start and end stations, want 2", len(terminals))
+		}
+		if len(trains) != len(schedule.Trains) {
+			t.Fatalf("got %d trains, want %d", len(trains), len(schedule.Trains))
+		}
+		paths := schedule.Paths()
+		for i, train := range schedule.Trains {
+			points := strings.Fields(trains[i].attr("points"))
+			if trains[i].title() != "T"+strconv.Itoa(train.ID) || len(points) != len(paths[train.ID]) {
+				t.Errorf("train %d: got %s with points %v, want %d points", train.ID, trains[i].title(), points, len(paths[train.ID]))
+			}
+			if got, want := trains[i].attr("stroke"), svgColors[train.ColorName()]; got != want {
+				t.Errorf("train %d: got colour %s, want %s", train.ID, got, want)
+			}
+		}
+	}
+}
+
+func TestWriteSVGScaling(t *testing.T) {
+	tests := []struct {
+		name     string
+		stations []network.Station
+		// want is where each station is drawn: the map starts at the
+		// margin and its longer side is svgMapSize pixels.
+		want map[string][2]float64
+	}{
+		{"negative coordinates", []network.Station{
+			{Name: "a", X: -10, Y: -10},
+			{Name: "b", X: 10, Y: 0},
+			{Name: "c", X: 0, Y: -5},
+		}, map[string][2]float64{
+			"a": {svgMargin, svgMargin},
+			"b": {svgMargin + svgMapSize, svgMargin + svgMapSize/2},
+			"c": {svgMargin + svgMapSize/2, svgMargin + svgMapSize/4},
+		}},
+		// A degree of latitude is as long everywhere, so the north-south
+		// map is svgMapSize high and the northern station is on top.
+		{"geographic", []network.Station{
+			{Name: "north", Lat: 61, Lon: 25, Geographic: true},
+			{Name: "south", Lat: 60, Lon: 25, Geographic: true},
+		}, map[string][2]float64{
+			"north": {svgMargin, svgMargin},
+			"south": {svgMargin, svgMargin + svgMapSize},
+		}},
+	}
+	for _, test := range tests {
+		net := network.NewNetwork()
+		if test.stations[0].Geographic {
+			net.Metadata.Coordinates = network.CoordinatesGeographic
+		}
+		for _, station := range test.stations {
+			net.AddStation(station)
+		}
+		net.AddConnection(network.Connection{Start: test.stations[0], End: test.stations[1]})
+
+		got := stationPoints(t, drawSVG(t, net, nil))
+		for name, want := range test.want {
+			point := got[name]
+			if math.Abs(point[0]-want[0]) > 0.1 || math.Abs(point[1]-want[1]) > 0.1 {
+				t.Errorf("%s: station %s drawn at %v, want %v", test.name, name, point, want)
+			}
+		}
+	}
+}
@@ -2,43 +2,105 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
-	network "stations/go/network/dijkstra"
+	"regexp"
 	"stations/go/parser"
 	"stations/go/pathfinder"
+	"stations/go/render"
 
 	"strconv"
 )
 
 func main() {
-	if len(os.Args) != 5 {
+	if len(os.Args) > 1 && os.Args[1] == "fmt" {
+		runFmt(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "convert" {
+		runConvert(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		runImport(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "dot" {
+		runDot(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		runLint(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "path" {
+		runPath(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "stations" {
+		runStations(os.Args[2:])
+		return
+	}
+
+	dotFile := flag.String("dot", "", "write the map with the route of every train as a Graphviz graph to `file`")
+	svgFile := flag.String("svg", "", "draw the map with the route of every train as an SVG picture to `file`")
+	animateFile := flag.String("animate", "", "write an animated SVG playback of the schedule to `file`")
+	mapFlags := addMapFlags(flag.CommandLine)
+	fuzzy := addFuzzyFlag(flag.CommandLine)
+	flag.Parse()
+	args := flag.Args()
+
+	// The start and end station may be left out when the map has defaults
+	if len(args) != 4 && len(args) != 2 {
 		fmt.Fprintln(os.Stderr, "Error: Incorrect number of arguments")
 		return
 	}
 
-	filePath := os.Args[1]
-	startStation := os.Args[2]
-	endStation := os.Args[3]
-	numTrains, err := strconv.Atoi(os.Args[4])
+	filePath := args[0]
+	startStation, endStation := "", ""
+	if len(args) == 4 {
+		startStation = args[1]
+		endStation = args[2]
+	}
+	numTrains, err := strconv.Atoi(args[len(args)-1])
 	if err != nil || numTrains <= 0 {
 		fmt.Fprintln(os.Stderr, "Error: Number of trains must be a positive integer")
 		return
 	}
 
-	connections, err := parser.ReadMap(filePath)
+	options, err := mapFlags.options()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "Error:", err)
 		return
 	}
 
-	if !isValidStation(connections, startStation) {
-		fmt.Fprintln(os.Stderr, "Error: Start station does not exist")
+	net, err := readNetwork(filePath, options)
+	if err != nil {
+		printMapError(err)
+		return
+	}
+
+	if len(args) == 2 {
+		startStation = net.Metadata.DefaultStart
+		endStation = net.Metadata.DefaultEnd
+		if startStation == "" || endStation == "" {
+			fmt.Fprintln(os.Stderr, "Error: Map has no default start and end station")
+			return
+		}
+	}
+
+	startStation, err = resolveStation(net, startStation, *fuzzy)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: Start station", err)
 		return
 	}
 
-	if !isValidStation(connections, endStation) {
-		fmt.Fprintln(os.Stderr, "Error: End station does not exist")
+	endStation, err = resolveStation(net, endStation, *fuzzy)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: End station", err)
 		return
 	}
 
@@ -47,7 +109,17 @@ func main() {
 		return
 	}
 
-	movements := pathfinder.ScheduleTrainMovements(startStation, endStation, connections, numTrains)
+	if _, err := pathfinder.FindShortestPath(startStation, endStation, net); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return
+	}
+
+	schedule, err := pathfinder.ScheduleTrains(startStation, endStation, net, numTrains)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return
+	}
+	movements := schedule.Lines()
 
 	fmt.Print("\nTrain movements from\033[1m ", filePath)
 	fmt.Print("\n\033[0m")
@@ -58,15 +130,100 @@ func main() {
 
 	fmt.Printf("\nTotal Movements: %d\n", len(movements))
 	fmt.Println("******************************************")
+
+	if *dotFile != "" {
+		if err := writeFile(*dotFile, func(w io.Writer) error {
+			return render.WriteDOT(w, net, &schedule)
+		}); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+	}
+	if *svgFile != "" {
+		if err := writeFile(*svgFile, func(w io.Writer) error {
+			return render.WriteSVG(w, net, &schedule)
+		}); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+	}
+	if *animateFile != "" {
+		if err := writeFile(*animateFile, func(w io.Writer) error {
+			return render.WriteAnimatedSVG(w, net, schedule, 1)
+		}); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+	}
+}
+
+// mapFlags are the flags that change the rules maps are checked by. The run
+// and every command that reads maps take them.
+type mapFlags struct {
+	maxStations, maxConnections           *int
+	stationNamePattern                    *string
+	ignoreDuplicates, negativeCoordinates *bool
+}
+
+// addMapFlags defines the flags that change the rules maps are checked by.
+func addMapFlags(flags *flag.FlagSet) mapFlags {
+	return mapFlags{
+		maxStations:         flags.Int("max-stations", parser.DefaultMaxStations, "most stations a map may have, 0 for no limit"),
+		maxConnections:      flags.Int("max-connections", parser.DefaultMaxConnections, "most connections a map may have, 0 for no limit"),
+		stationNamePattern:  flags.String("station-names", "", "regular expression station names have to match instead of ^[a-z0-9_]+$"),
+		ignoreDuplicates:    flags.Bool("ignore-duplicates", false, "keep the first of duplicate stations and connections instead of failing"),
+		negativeCoordinates: flags.Bool("negative-coordinates", false, "allow stations to have coordinates below 0"),
+	}
 }
 
-func isValidStation(connections network.Connections, station string) bool {
-	// Create a map of station names for quick lookup
-	stationNames := make(map[string]struct{})
-	for _, connection := range connections {
-		stationNames[connection.Start.Name] = struct{}{}
-		stationNames[connection.End.Name] = struct{}{}
+// addFuzzyFlag defines the flag that lets station names given on the
+// command line be fuzzy, see resolveStation.
+func addFuzzyFlag(flags *flag.FlagSet) *bool {
+	return flags.Bool("fuzzy", false, "accept a station name in the wrong case, or the start of a name, when it matches only one station")
+}
+
+// options returns the parse options the flags give, or an error for a
+// negative limit or an invalid station name rule.
+func (f mapFlags) options() (parser.ParseOptions, error) {
+	if *f.maxStations < 0 || *f.maxConnections < 0 {
+		return parser.ParseOptions{}, errors.New("-max-stations and -max-connections must be 0 or more")
+	}
+	options := parser.ParseOptions{
+		MaxStations:         noLimit(*f.maxStations),
+		MaxConnections:      noLimit(*f.maxConnections),
+		NegativeCoordinates: *f.negativeCoordinates,
+	}
+	if *f.ignoreDuplicates {
+		options.Duplicates = parser.DuplicatesIgnored
+	}
+	if *f.stationNamePattern != "" {
+		stationName, err := regexp.Compile(*f.stationNamePattern)
+		if err != nil {
+			return parser.ParseOptions{}, fmt.Errorf("Invalid -station-names: %v", err)
+		}
+		options.StationName = stationName
+	}
+	return options, nil
+}
+
+// noLimit turns a limit of 0 given on the command line into parser.NoLimit.
+func noLimit(limit int) int {
+	if limit == 0 {
+		return parser.NoLimit
+	}
+	return limit
+}
+
+// printMapError prints every diagnostic of a map that failed to parse, one
+// per line, errors starting with "Error:" like every other error.
+func printMapError(err error) {
+	var diagnostics parser.Diagnostics
+	if !errors.As(err, &diagnostics) {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return
+	}
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == parser.SeverityError {
+			fmt.Fprintln(os.Stderr, "Error:", diagnostic)
+		} else {
+			fmt.Fprintln(os.Stderr, diagnostic)
+		}
 	}
-	_, exists := stationNames[station]
-	return exists
 }
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runMain runs the command with args and returns what it printed to stdout
+// and stderr.
+func runMain(t *testing.T, args ...string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	stdout, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdout.Close()
+	stderr, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stderr.Close()
+
+	oldArgs, oldStdout, oldStderr := os.Args, os.Stdout, os.Stderr
+	defer func() {
+		os.Args, os.Stdout, os.Stderr = oldArgs, oldStdout, oldStderr
+	}()
+	os.Args = append([]string{"trains"}, args...)
+	os.Stdout, os.Stderr = stdout, stderr
+	// main defines its flags on the command line flag set every run.
+	flag.CommandLine = flag.NewFlagSet("trains", flag.ExitOnError)
+	main()
+
+	out, err := os.ReadFile(stdout.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	errOut, err := os.ReadFile(stderr.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out), string(errOut)
+}
+
+func TestErrorMaps(t *testing.T) {
+	tests := []struct {
+		file       string
+		start, end string
+	}{
+		{"10no-start-station_london.txt", "waterloo", "victoria"},
+		{"11no-end-station_london.txt", "waterloo", "victoria"},
+		{"12same-start-end_london.txt", "waterloo", "victoria"},
+		{"13no-path_london.txt", "waterloo", "st_pancras"},
+		{"14duplicate-routes_london.txt", "waterloo", "victoria"},
+		{"16no-valid-coord_london.txt", "waterloo", "victoria"},
+		{"17same-coords_london.txt", "waterloo", "victoria"},
+		{"18station-not-exist_london.txt", "waterloo", "victoria"},
+		{"19duplicate-names_london.txt", "waterloo", "victoria"},
+		{"21no-stations_london.txt", "waterloo", "victoria"},
+		{"22no-connections_london.txt", "waterloo", "victoria"},
+		{"23over-tenK.txt", "station2", "station4"},
+	}
+	for _, test := range tests {
+		stdout, stderr := runMain(t, filepath.Join("maps", "errors", test.file), test.start, test.end, "4")
+		if stdout != "" {
+			t.Errorf("%s: got stdout %q, want none", test.file, stdout)
+		}
+		lines := strings.Split(strings.TrimSuffix(stderr, "\n"), "\n")
+		for _, line := range lines {
+			if !strings.HasPrefix(line, "Error: ") {
+				t.Errorf("%s: got stderr line %q, want it to start with Error", test.file, line)
+			}
+		}
+	}
+}
+
+// A map with several problems prints each of them in the order of the map.
+func TestErrorMapWithSeveralDiagnostics(t *testing.T) {
+	file := filepath.Join("maps", "errors", "19duplicate-names_london.txt")
+	_, stderr := runMain(t, file, "waterloo", "victoria", "4")
+	want := "Error: " + file + ":3:1: E011 duplicate station name: waterloo\n" +
+		"Error: " + file + ":8:10: E016 connection to non-existent station: victoria\n" +
+		"Error: " + file + ":11:1: E015 connection from non-existent station: victoria\n"
+	if stderr != want {
+		t.Errorf("got stderr\n%s\nwant\n%s", stderr, want)
+	}
+}
@@ -0,0 +1,153 @@
+// stations.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	network "stations/go/network/dijkstra"
+	"strings"
+)
+
+// maxSuggestions is how many similar names are suggested for a station that
+// does not exist.
+const maxSuggestions = 3
+
+// runStations lists the station names of a map, one per line in order, or
+// only the names matching a regular expression.
+func runStations(args []string) {
+	flags := flag.NewFlagSet("stations", flag.ExitOnError)
+	mapFlags := addMapFlags(flags)
+	flags.Parse(args)
+	args = flags.Args()
+	if len(args) != 1 && len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "Error: Usage: trains stations [map flags] <map> [pattern]")
+		os.Exit(1)
+	}
+
+	var pattern *regexp.Regexp
+	if len(args) == 2 {
+		var err error
+		pattern, err = regexp.Compile(args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error: Invalid pattern:", err)
+			os.Exit(1)
+		}
+	}
+
+	net, err := readNetwork(args[0], commandOptions(mapFlags))
+	if err != nil {
+		printMapError(err)
+		os.Exit(1)
+	}
+	for _, name := range net.StationNames() {
+		if pattern == nil || pattern.MatchString(name) {
+			fmt.Println(name)
+		}
+	}
+}
+
+// resolveStation returns the station of the network that name stands for.
+// A name must match a station exactly, unless fuzzy is set: then a name that
+// matches exactly one station ignoring case, or else is the start of exactly
+// one station's name, stands for that station. The error of a name that
+// stands for no station suggests the most similar names.
+func resolveStation(net *network.Network, name string, fuzzy bool) (string, error) {
+	if net.HasStation(name) {
+		return name, nil
+	}
+
+	if fuzzy {
+		lower := strings.ToLower(name)
+		var sameName, prefixed []string
+		for _, station := range net.StationNames() {
+			if strings.ToLower(station) == lower {
+				sameName = append(sameName, station)
+			}
+			if strings.HasPrefix(strings.ToLower(station), lower) {
+				prefixed = append(prefixed, station)
+			}
+		}
+		if len(sameName) == 1 {
+			return sameName[0], nil
+		}
+		if len(prefixed) == 1 {
+			return prefixed[0], nil
+		}
+		if len(prefixed) > 1 {
+			return "", fmt.Errorf("%s could be any of %s", name, orList(prefixed, maxSuggestions))
+		}
+	}
+
+	suggestions := suggestStations(net, name)
+	if len(suggestions) == 0 {
+		return "", fmt.Errorf("%s does not exist", name)
+	}
+	return "", fmt.Errorf("%s does not exist, did you mean %s?", name, orList(suggestions, maxSuggestions))
+}
+
+// suggestStations returns the station names closest to name by edit
+// distance, closest first, leaving out names too different to be a typo.
+func suggestStations(net *network.Network, name string) []string {
+	lower := strings.ToLower(name)
+	limit := max(2, len([]rune(name))/3)
+
+	var suggestions []string
+	distances := make(map[string]int)
+	for _, station := range net.StationNames() {
+		distance := editDistance(lower, strings.ToLower(station))
+		if distance <= limit {
+			suggestions = append(suggestions, station)
+			distances[station] = distance
+		}
+	}
+	// net.StationNames() is sorted, so names as close as each other stay
+	// in alphabetical order.
+	sort.SliceStable(suggestions, func(i, j int) bool { return distances[suggestions[i]] < distances[suggestions[j]] })
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+	return suggestions
+}
+
+// editDistance returns the Levenshtein distance between a and b: how many
+// characters have to be inserted, deleted or replaced to turn one into the
+// other.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+// orList joins at most limit names as "a, b or c", with "..." for the rest.
+func orList(names []string, limit int) string {
+	more := len(names) > limit
+	if more {
+		names = names[:limit]
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+	list := strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+	if more {
+		list = strings.Join(names, ", ") + " or ..."
+	}
+	return list
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"reflect"
+	"stations/go/parser"
+	"strings"
+	"testing"
+)
+
+// londonStations is a map whose station names are close to each other.
+const londonStations = `stations:
+waterloo,0,0
+victoria,1,0
+euston,2,0
+st_pancras,3,0
+st_paul,4,0
+bank,5,0
+band,6,0
+
+connections:
+waterloo-victoria
+victoria-euston
+euston-st_pancras
+st_pancras-st_paul
+st_paul-bank
+bank-band
+`
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"bank", "bank", 0},
+		{"", "bank", 4},
+		{"bank", "band", 1},
+		{"bank", "bnk", 1},
+		{"bank", "banks", 1},
+		{"kitten", "sitting", 3},
+		{"äbc", "abc", 1},
+	}
+	for _, test := range tests {
+		if got := editDistance(test.a, test.b); got != test.want {
+			t.Errorf("editDistance(%q, %q): got %d, want %d", test.a, test.b, got, test.want)
+		}
+		if got := editDistance(test.b, test.a); got != test.want {
+			t.Errorf("editDistance(%q, %q): got %d, want %d", test.b, test.a, got, test.want)
+		}
+	}
+}
+
+func TestSuggestStations(t *testing.T) {
+	net, err := parser.ParseNetwork(strings.NewReader(londonStations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"waterloo", []string{"waterloo"}},
+		{"WATERLOO", []string{"waterloo"}},
+		{"watrloo", []string{"waterloo"}},
+		{"heathrow", nil},
+		// Names as close as each other are in alphabetical order.
+		{"banx", []string{"band", "bank"}},
+		// Closer names come first.
+		{"bamk", []string{"bank", "band"}},
+	}
+	for _, test := range tests {
+		if got := suggestStations(net, test.name); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestResolveStation(t *testing.T) {
+	net, err := parser.ParseNetwork(strings.NewReader(londonStations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		fuzzy bool
+		// want is the station, or else the error.
+		want, wantErr string
+	}{
+		{"waterloo", false, "waterloo", ""},
+		{"Waterloo", false, "", "Waterloo does not exist, did you mean waterloo?"},
+		{"Waterloo", true, "waterloo", ""},
+		{"watrloo", false, "", "watrloo does not exist, did you mean waterloo?"},
+		{"watrloo", true, "", "watrloo does not exist, did you mean waterloo?"},
+		{"heathrow", true, "", "heathrow does not exist"},
+		{"banx", false, "", "banx does not exist, did you mean band or bank?"},
+		{"eus", true, "euston", ""},
+		{"st_pa", true, "", "st_pa could be any of st_pancras or st_paul"},
+	}
+	for _, test := range tests {
+		got, err := resolveStation(net, test.name, test.fuzzy)
+		gotErr := ""
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if got != test.want || gotErr != test.wantErr {
+			t.Errorf("%s, fuzzy %v: got %q, %q, want %q, %q", test.name, test.fuzzy, got, gotErr, test.want, test.wantErr)
+		}
+	}
+}